@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- ACCESS LOG ---
+
+const requestIDHeader = "X-Request-ID"
+
+// accessLogger writes one JSON object per request to stdout for the log store.
+var accessLogger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// accessLog replaces gin's text logger with a structured line per request.
+// The request ID is the client's X-Request-ID when it sends one, otherwise a
+// new ID; either way it is echoed back so both sides can quote it.
+func accessLog(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = cfg.idGenerator().NewID()
+		}
+		c.Set("requestId", requestID)
+		c.Header(requestIDHeader, requestID)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latencyMs", float64(time.Since(start).Microseconds())/1000),
+			slog.String("requestId", requestID),
+			slog.String("clientIp", c.ClientIP()),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		accessLogger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
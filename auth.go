@@ -0,0 +1,38 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- ADMIN AUTH ---
+
+// requireAdminToken guards ops-only endpoints. Callers send ADMIN_TOKEN as
+// "Authorization: Bearer <token>"; with no token configured the endpoints
+// stay switched off rather than open.
+func requireAdminToken(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.AdminToken == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Admin endpoints are disabled: ADMIN_TOKEN is not set", "code": "ADMIN_AUTH_DISABLED"})
+			return
+		}
+		if !hasAdminToken(cfg, c) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid admin token", "code": "UNAUTHORIZED"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasAdminToken reports whether the request carries ADMIN_TOKEN, for admin-only
+// options on otherwise public endpoints. Always false when no token is set.
+func hasAdminToken(cfg Config, c *gin.Context) bool {
+	if cfg.AdminToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(cfg.AdminToken)) == 1
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- CONFIRMATION CODE AUTOCOMPLETE ---
+
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 25
+	minAutocompletePrefix    = 3 // shorter prefixes match too much to be useful
+)
+
+// BookingSuggestion is the little a type-ahead shows per match.
+type BookingSuggestion struct {
+	ConfirmationCode string `json:"confirmationCode"`
+	VehicleID        string `json:"vehicleId"`
+	Status           string `json:"status"`
+}
+
+// handleBookingAutocomplete answers GET /bookings/autocomplete?prefix=CONF-12
+// with up to ?limit= bookings whose confirmation code starts with the prefix,
+// in code order. There's no total count; it only ever reads one small page.
+func handleBookingAutocomplete(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		prefix := c.Query("prefix")
+		if len(prefix) < minAutocompletePrefix {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("prefix must be at least %d characters", minAutocompletePrefix)})
+			return
+		}
+		limit := int64(defaultAutocompleteLimit)
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 1 || n > maxAutocompleteLimit {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxAutocompleteLimit)})
+				return
+			}
+			limit = n
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		q := BookingQuery{
+			CodePrefix: prefix,
+			List:       ListParams{Page: pageParams{Limit: limit}, SortField: "confirmationCode", SortAsc: true},
+		}
+		suggestions := []BookingSuggestion{}
+		err := store.EachBooking(ctx, q, func(b DBBooking) error {
+			suggestions = append(suggestions, BookingSuggestion{ConfirmationCode: b.ConfirmationCode, VehicleID: b.VehicleID, Status: b.Status})
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search bookings"})
+			return
+		}
+		c.JSON(http.StatusOK, suggestions)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// --- BOOKING ACTIONS ---
+
+// ActionEmergency marks a breakdown that must be seen today, full or not.
+const ActionEmergency = "EMERGENCY"
+
+// selectionTweak changes how auto-assignment treats a booking's action.
+type selectionTweak struct {
+	// AllowOverbooking books the least busy active center when every center
+	// is full, instead of failing.
+	AllowOverbooking bool
+}
+
+// selectionTweaks maps booking actions to their selection changes. Actions
+// without an entry select centers the usual way.
+var selectionTweaks = map[string]selectionTweak{
+	ActionEmergency: {AllowOverbooking: true},
+}
+
+// priorityTweaks maps booking priorities to their selection changes. High
+// priority fleet contracts get a slot even when every center is full, the
+// same way an emergency does.
+var priorityTweaks = map[string]selectionTweak{
+	PriorityHigh: {AllowOverbooking: true},
+}
+
+// tweaksFor combines the selection changes of a booking's action and
+// priority.
+func tweaksFor(action, priority string) selectionTweak {
+	priority, _ = normalizePriority(priority)
+	byAction, byPriority := selectionTweaks[normalizeAction(action)], priorityTweaks[priority]
+	return selectionTweak{AllowOverbooking: byAction.AllowOverbooking || byPriority.AllowOverbooking}
+}
+
+// normalizeAction upper-cases a client action so lookups and filters match
+// however it was sent.
+func normalizeAction(action string) string {
+	return strings.ToUpper(strings.TrimSpace(action))
+}
+
+// leastBookedCenter returns the least busy active center ignoring capacity,
+// or nil when there is no active center at all.
+func (rules capacityRules) leastBookedCenter(centers []ServiceCenterDBModel, at time.Time) *ServiceCenterDBModel {
+	var best *ServiceCenterDBModel
+	for i := range centers {
+		if centers[i].ID == "" || !centers[i].IsActive || strings.TrimSpace(centers[i].Name) == "" {
+			continue
+		}
+		if best == nil || rules.bookedSlotsAt(centers[i], at) < rules.bookedSlotsAt(*best, at) {
+			best = &centers[i]
+		}
+	}
+	return best
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleBookingCheck answers whether POST /book-service could auto-assign a
+// center for this request right now, without creating or reserving
+// anything. It takes the same body, so the UI can ask before showing its
+// confirm button. The answer is a snapshot; a center can still fill up
+// before the booking is made.
+func handleBookingCheck(cfg Config, lookup *centerLookup) gin.HandlerFunc {
+	rules := cfg.capacityRules()
+	return func(c *gin.Context) {
+		var req IncomingBookingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: " + err.Error()})
+			return
+		}
+
+		company, problem := checkVehicleID(cfg, req.VehicleID)
+		if problem != nil {
+			c.JSON(http.StatusOK, gin.H{"available": false, "reason": problem.Code, "error": problem.Reason})
+			return
+		}
+
+		var scheduledAt time.Time
+		if req.ScheduledService.DateTime != "" {
+			var err error
+			if scheduledAt, err = parseFlexibleTime(req.ScheduledService.DateTime); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduledService.dateTime: " + err.Error()})
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		centers, err := lookup.centers(ctx, company)
+		if err != nil {
+			respondCenterLookupError(c, err)
+			return
+		}
+
+		if ranked := rules.rankCenters(centers, scheduledAt, preferredCenters(cfg, company)); len(ranked) > 0 {
+			c.JSON(http.StatusOK, gin.H{"available": true, "center": rules.centerAvailability(*ranked[0], scheduledAt)})
+			return
+		}
+		if overbook := rules.leastBookedCenter(centers, scheduledAt); overbook != nil && tweaksFor(req.Action, req.Priority).AllowOverbooking {
+			c.JSON(http.StatusOK, gin.H{"available": true, "center": rules.centerAvailability(*overbook, scheduledAt), "overbooked": true})
+			return
+		}
+		if fallbackID, ok := fallbackCenter(cfg, company); ok {
+			c.JSON(http.StatusOK, gin.H{"available": true, "center": gin.H{"centerId": fallbackID}, "fallbackUsed": true})
+			return
+		}
+
+		_, body := rules.noCenterSelected(centers, scheduledAt)
+		c.JSON(http.StatusOK, gin.H{"available": false, "reason": body["code"], "error": body["error"]})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bookingLocation is the URL a booking can be fetched from, as sent in the
+// Location header after it is created.
+func bookingLocation(confirmationCode string) string {
+	return "/bookings/" + url.PathEscape(confirmationCode)
+}
+
+// handleGetBooking returns one booking by confirmation code. Codes are chosen
+// by clients and not guaranteed unique, so the newest booking wins.
+func handleGetBooking(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		booking, err := store.FindBookingByCode(ctx, c.Param("confirmationCode"))
+		if err == errBookingNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found", "code": "BOOKING_NOT_FOUND"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking"})
+			return
+		}
+
+		respondJSONWithETag(c, booking)
+	}
+}
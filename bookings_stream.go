@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamBookingsJSON writes GET /bookings?stream=true as a JSON array, one
+// booking at a time as it comes off the cursor, so dashboards can start
+// rendering large listings early and the list never sits in memory. Streamed
+// responses carry no ETag. A failure midway leaves the array unterminated,
+// which clients see as a parse error rather than a silently short list.
+func streamBookingsJSON(ctx context.Context, c *gin.Context, q BookingQuery) {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	if _, err := w.WriteString("["); err != nil {
+		return
+	}
+	first := true
+	err := store.EachBooking(ctx, q, func(booking DBBooking) error {
+		doc, err := json.Marshal(booking)
+		if err != nil {
+			return err
+		}
+		if !first {
+			doc = append([]byte(","), doc...)
+		}
+		first = false
+		if _, err := w.Write(doc); err != nil {
+			return errClientGone
+		}
+		w.Flush()
+		return nil
+	})
+	if err != nil {
+		if err != errClientGone {
+			fmt.Println("❌ Streaming bookings failed:", err)
+		}
+		return
+	}
+	w.WriteString("]")
+}
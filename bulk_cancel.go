@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BulkCancelRequest struct {
+	ServiceCenterID string `json:"serviceCenterId"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Confirm         bool   `json:"confirm"`
+}
+
+// BulkItemResult is one item's outcome in a bulk response. Status is the
+// HTTP status the item would have got on its own.
+type BulkItemResult struct {
+	Index            int    `json:"index"`
+	Status           int    `json:"status"`
+	ConfirmationCode string `json:"confirmationCode"`
+	Error            string `json:"error,omitempty"`
+}
+
+// bulkStatus is 200 when every item succeeded (or there were none), 400 when
+// every item failed and 207 Multi-Status for a mix.
+func bulkStatus(results []BulkItemResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Status >= http.StatusBadRequest {
+			failed++
+		}
+	}
+	switch {
+	case failed == 0:
+		return http.StatusOK
+	case failed == len(results):
+		return http.StatusBadRequest
+	}
+	return http.StatusMultiStatus
+}
+
+// handleBulkCancel cancels every active booking matching a center and/or
+// scheduled-time range, e.g. when a center goes offline. Each cancellation is
+// logged and its slot released in 'auto_ai_db'. The response lists every
+// booking's outcome; see bulkStatus for the status code.
+func handleBulkCancel(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BulkCancelRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: " + err.Error()})
+			return
+		}
+		if !req.Confirm && c.Query("confirm") != "true" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bulk cancellation requires confirm=true"})
+			return
+		}
+		if req.ServiceCenterID == "" && req.From == "" && req.To == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Provide serviceCenterId and/or a from/to range"})
+			return
+		}
+
+		q := BookingQuery{CenterID: req.ServiceCenterID, ActiveOnly: true}
+		var from, to time.Time
+		for bound, value := range map[*time.Time]string{&from: req.From, &to: req.To} {
+			if value == "" {
+				continue
+			}
+			t, err := parseFlexibleTime(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range: " + err.Error()})
+				return
+			}
+			*bound = t
+		}
+		q.ScheduledFrom = from
+		if !to.IsZero() {
+			// "to" is inclusive and stored times have whole seconds.
+			q.ScheduledTo = to.Add(time.Second)
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		bookings, _, err := store.FindBookings(ctx, q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+			return
+		}
+
+		cancelled := []string{}
+		failed := []string{}
+		results := []BulkItemResult{}
+		for i, booking := range bookings {
+			result := BulkItemResult{Index: i, Status: http.StatusOK, ConfirmationCode: booking.ConfirmationCode}
+			err := cancelBooking(ctx, cfg, booking, "BULK_CANCELLED")
+			switch {
+			case errors.Is(err, errBookingNotFound):
+				failed = append(failed, booking.ConfirmationCode)
+				result.Status, result.Error = http.StatusConflict, "Booking changed before it could be cancelled"
+			case err != nil:
+				fmt.Printf("❌ Bulk cancel failed for %s: %v\n", booking.ConfirmationCode, err)
+				failed = append(failed, booking.ConfirmationCode)
+				result.Status, result.Error = http.StatusInternalServerError, "Failed to cancel booking"
+			default:
+				cancelled = append(cancelled, booking.ConfirmationCode)
+			}
+			results = append(results, result)
+		}
+
+		c.JSON(bulkStatus(results), gin.H{
+			"cancelled":         len(cancelled),
+			"confirmationCodes": cancelled,
+			"failed":            failed,
+			"results":           results,
+		})
+	}
+}
+
+// cancelBooking marks one booking cancelled together with its log entry, then
+// frees its center slot. It returns errBookingNotFound, without logging or
+// releasing anything, when the booking is no longer in the state it was read
+// in. A failed slot release is reported but not fatal.
+func cancelBooking(ctx context.Context, cfg Config, booking DBBooking, action string) error {
+	cancelled := booking
+	cancelled.Status = StatusCancelled
+	if err := store.SetBookingStatus(ctx, booking, StatusCancelled, ActorOps, bookingLogEntry(cfg, cancelled, action)); err != nil {
+		return err
+	}
+
+	if err := releaseCenterSlot(ctx, booking); err != nil {
+		fmt.Printf("⚠️ Could not release slot at %s for %s: %v\n", booking.ScheduledService.ServiceCenterID, booking.ConfirmationCode, err)
+	}
+	return nil
+}
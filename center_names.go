@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// handleGetCenterNames returns the sorted, distinct center names our bookings
+// are assigned to, optionally for one company, for filter dropdowns.
+func handleGetCenterNames(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := bson.M{"scheduledService.serviceCenterName": bson.M{"$nin": bson.A{"", nil}}}
+		if company := c.Query("company"); company != "" {
+			if err := validateCompanyName(company); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_COMPANY"})
+				return
+			}
+			for k, v := range companyVehicleFilter(company) {
+				filter[k] = v
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		values, err := bookingCollection.Distinct(ctx, "scheduledService.serviceCenterName", filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch center names"})
+			return
+		}
+
+		names := make([]string, 0, len(values))
+		for _, v := range values {
+			if name, ok := v.(string); ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		c.JSON(http.StatusOK, names)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// --- SERVICE CENTER PROVIDERS ---
+
+// CenterProvider is where the service center list comes from. Centers are
+// shared between companies today, so company is a routing hint that
+// providers may ignore; "" asks for every center.
+type CenterProvider interface {
+	CentersForCompany(ctx context.Context, company string) ([]ServiceCenterDBModel, error)
+}
+
+// centerProvider is chosen in main; nil means there is no center source.
+var centerProvider CenterProvider
+
+// Where centers are read from, set from CENTER_SOURCE. CENTERS_FILE
+// overrides CenterSourceMongo.
+const (
+	CenterSourceMongo    = "mongo"     // 'auto_ai_db.service_centers'
+	CenterSourceAdminAPI = "admin-api" // the admin API of each company's region
+)
+
+// mongoCenterProvider reads 'auto_ai_db.service_centers'.
+type mongoCenterProvider struct{}
+
+func (mongoCenterProvider) CentersForCompany(ctx context.Context, _ string) ([]ServiceCenterDBModel, error) {
+	cursor, err := serviceCenterCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("query service centers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var centers []ServiceCenterDBModel
+	if err = cursor.All(ctx, &centers); err != nil {
+		return nil, fmt.Errorf("decode service centers: %w", err)
+	}
+	return centers, nil
+}
+
+// fileCenterProvider serves a fixed center list from a JSON file, for
+// STORE=memory and local work without access to 'auto_ai_db'. The file is read
+// once at startup; slots booked against it are not written back.
+type fileCenterProvider struct {
+	centers []ServiceCenterDBModel
+}
+
+func newFileCenterProvider(path string) (*fileCenterProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read centers file: %w", err)
+	}
+	var centers []ServiceCenterDBModel
+	if err := json.Unmarshal(data, &centers); err != nil {
+		return nil, fmt.Errorf("parse centers file %s: %w", path, err)
+	}
+	return &fileCenterProvider{centers: centers}, nil
+}
+
+func (p *fileCenterProvider) CentersForCompany(context.Context, string) ([]ServiceCenterDBModel, error) {
+	// Callers keep pointers into the result, so each gets its own copy.
+	return append([]ServiceCenterDBModel(nil), p.centers...), nil
+}
+
+// adminAPICentersPath is where an admin API lists its centers, as a JSON
+// array in the CENTERS_FILE shape.
+const adminAPICentersPath = "/api/service-centers"
+
+const adminAPITimeout = 10 * time.Second
+
+// The admin APIs run on hosts that put an instance to sleep after
+// adminAPIIdleAfter without traffic and take tens of seconds to start it
+// again. With ADMIN_API_WAKE_UP, a cheap HEAD waits out that start so the
+// center read itself isn't cut short by adminAPITimeout.
+const (
+	adminAPIIdleAfter   = 15 * time.Minute
+	adminAPIWakeTimeout = time.Minute
+)
+
+// adminAPICenterProvider reads centers from the admin APIs of the regions a
+// company is routed to in ADMIN_API_ROUTES, or from ADMIN_API_URL for
+// companies without a route. Asking for "" reads every configured admin API.
+// Like fileCenterProvider it is read-only: there is nowhere to reserve slots
+// or sync bookings, so loadConfig only allows it with STORE=memory.
+type adminAPICenterProvider struct {
+	defaultURL string
+	routes     map[string][]string
+	client     *http.Client
+
+	wakeUp     bool
+	wakeClient *http.Client
+	mu         sync.Mutex
+	lastAnswer map[string]time.Time // base URL -> when it last answered
+}
+
+func newAdminAPICenterProvider(cfg Config) *adminAPICenterProvider {
+	return &adminAPICenterProvider{
+		defaultURL: cfg.AdminAPIURL,
+		routes:     cfg.AdminAPIRoutes,
+		client:     &http.Client{Timeout: adminAPITimeout},
+		wakeUp:     cfg.AdminAPIWakeUp,
+		wakeClient: &http.Client{Timeout: adminAPIWakeTimeout},
+		lastAnswer: map[string]time.Time{},
+	}
+}
+
+// baseURLs are the admin APIs that serve company's centers.
+func (p *adminAPICenterProvider) baseURLs(company string) []string {
+	if company != "" {
+		if urls, ok := p.routes[strings.ToLower(company)]; ok {
+			return urls
+		}
+		return []string{p.defaultURL}
+	}
+	urls := []string{p.defaultURL}
+	for _, routed := range p.routes {
+		for _, baseURL := range routed {
+			if !slices.Contains(urls, baseURL) {
+				urls = append(urls, baseURL)
+			}
+		}
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+// CentersForCompany asks every region at once and merges what answered, so
+// one region's admin API being down doesn't stop bookings in the others. It
+// fails only when no region answered.
+func (p *adminAPICenterProvider) CentersForCompany(ctx context.Context, company string) ([]ServiceCenterDBModel, error) {
+	urls := p.baseURLs(company)
+	results := make([][]ServiceCenterDBModel, len(urls))
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, baseURL := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = p.fetch(ctx, baseURL)
+		}()
+	}
+	wg.Wait()
+
+	var centers []ServiceCenterDBModel
+	failed := 0
+	for i := range urls {
+		if errs[i] != nil {
+			failed++
+			continue
+		}
+		centers = append(centers, results[i]...)
+	}
+	if failed == len(urls) {
+		return nil, errors.Join(errs...)
+	}
+	for i, baseURL := range urls {
+		if errs[i] != nil {
+			fmt.Printf("⚠️ Admin API %s failed, serving centers from the other regions: %v\n", baseURL, errs[i])
+		}
+	}
+	return centers, nil
+}
+
+func (p *adminAPICenterProvider) fetch(ctx context.Context, baseURL string) ([]ServiceCenterDBModel, error) {
+	if p.wakeUp && p.mayBeAsleep(baseURL) {
+		p.wake(ctx, baseURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+adminAPICentersPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch centers from %s: %w", baseURL, err)
+	}
+	defer resp.Body.Close()
+	p.answered(baseURL)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read centers from %s: %w", baseURL, err)
+	}
+
+	// A sleeping or crashed host answers with its own HTML error page, often
+	// with a 2xx or 502; say so instead of surfacing a cryptic decode error.
+	contentType := resp.Header.Get("Content-Type")
+	if !isJSONContentType(contentType) {
+		return nil, badUpstreamResponse(baseURL, resp, body, fmt.Errorf("content type %q is not JSON", contentType))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("admin API %s answered %s", baseURL, resp.Status)
+	}
+	var centers []ServiceCenterDBModel
+	if err := json.Unmarshal(body, &centers); err != nil {
+		return nil, badUpstreamResponse(baseURL, resp, body, err)
+	}
+	return centers, nil
+}
+
+// mayBeAsleep reports whether baseURL hasn't answered for adminAPIIdleAfter,
+// or not at all since startup.
+func (p *adminAPICenterProvider) mayBeAsleep(baseURL string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	last, ok := p.lastAnswer[baseURL]
+	return !ok || time.Since(last) > adminAPIIdleAfter
+}
+
+func (p *adminAPICenterProvider) answered(baseURL string) {
+	p.mu.Lock()
+	p.lastAnswer[baseURL] = time.Now()
+	p.mu.Unlock()
+}
+
+// wake sends a HEAD to baseURL and waits, up to adminAPIWakeTimeout or ctx,
+// for any answer. A failure is only logged; the read that follows reports it.
+func (p *adminAPICenterProvider) wake(ctx context.Context, baseURL string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, strings.TrimRight(baseURL, "/")+adminAPICentersPath, nil)
+	if err != nil {
+		return
+	}
+	start := time.Now()
+	resp, err := p.wakeClient.Do(req)
+	if err != nil {
+		fmt.Printf("⚠️ Admin API %s did not answer the wake-up HEAD: %v\n", baseURL, err)
+		return
+	}
+	resp.Body.Close()
+	p.answered(baseURL)
+	fmt.Printf("Admin API %s answered the wake-up HEAD in %s\n", baseURL, time.Since(start).Round(time.Millisecond))
+}
+
+// wakeAll wakes every configured admin API at once, so the first bookings
+// after a deploy don't each wait for a region to start.
+func (p *adminAPICenterProvider) wakeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, baseURL := range p.baseURLs("") {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.wake(ctx, baseURL)
+		}()
+	}
+	wg.Wait()
+}
+
+// isJSONContentType accepts application/json and +json types. A missing
+// header gets the benefit of the doubt; the decode still checks the body.
+func isJSONContentType(value string) bool {
+	if value == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(value)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// upstreamBadResponseError is an admin API answer that isn't a JSON center
+// list. Handlers answer it with 502 UPSTREAM_BAD_RESPONSE.
+type upstreamBadResponseError struct {
+	baseURL string
+	status  string
+	cause   error
+}
+
+func (e *upstreamBadResponseError) Error() string {
+	return fmt.Sprintf("admin API %s answered %s with an unusable body: %v", e.baseURL, e.status, e.cause)
+}
+
+func (e *upstreamBadResponseError) Unwrap() error { return e.cause }
+
+// upstreamSnippetLength is how much of a bad body is logged for diagnosis.
+const upstreamSnippetLength = 200
+
+func badUpstreamResponse(baseURL string, resp *http.Response, body []byte, cause error) error {
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if len(snippet) > upstreamSnippetLength {
+		snippet = snippet[:upstreamSnippetLength] + "..."
+	}
+	fmt.Printf("❌ Admin API %s answered %s (%s): %q\n", baseURL, resp.Status, resp.Header.Get("Content-Type"), snippet)
+	return &upstreamBadResponseError{baseURL: baseURL, status: resp.Status, cause: cause}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func adminAPIServer(t *testing.T, status int, contentType, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != adminAPICentersPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAdminAPIHTMLResponse(t *testing.T) {
+	const page = "<!DOCTYPE html><html><body><h1>502 Bad Gateway</h1></body></html>"
+	for _, status := range []int{http.StatusOK, http.StatusBadGateway} {
+		srv := adminAPIServer(t, status, "text/html; charset=utf-8", page)
+		cfg := testConfig(t)
+		cfg.AdminAPIURL = srv.URL
+
+		_, err := newAdminAPICenterProvider(cfg).CentersForCompany(context.Background(), "acme")
+		var bad *upstreamBadResponseError
+		if !errors.As(err, &bad) {
+			t.Fatalf("status %d: err = %v, want an upstreamBadResponseError", status, err)
+		}
+
+		newTestRouter(t, cfg)
+		centerProvider = newAdminAPICenterProvider(cfg)
+		r := newRouter(cfg, newDeps(cfg))
+		w := serve(r, http.MethodPost, "/book-service", autoBooking)
+		var body struct {
+			Code string `json:"code"`
+		}
+		decode(t, w, &body)
+		if w.Code != http.StatusBadGateway || body.Code != "UPSTREAM_BAD_RESPONSE" {
+			t.Errorf("status %d: booking got %d %s, want 502 UPSTREAM_BAD_RESPONSE", status, w.Code, w.Body)
+		}
+	}
+}
+
+func TestAdminAPIUndecodableJSON(t *testing.T) {
+	srv := adminAPIServer(t, http.StatusOK, "application/json", `{"centers": "soon"}`)
+	cfg := testConfig(t)
+	cfg.AdminAPIURL = srv.URL
+
+	_, err := newAdminAPICenterProvider(cfg).CentersForCompany(context.Background(), "")
+	var bad *upstreamBadResponseError
+	if !errors.As(err, &bad) {
+		t.Errorf("err = %v, want an upstreamBadResponseError", err)
+	}
+}
+
+func TestAdminAPICenters(t *testing.T) {
+	srv := adminAPIServer(t, http.StatusOK, "application/json; charset=utf-8", `[{"centerId":"C1","name":"One","capacity":"3","is_active":true}]`)
+	cfg := testConfig(t)
+	cfg.AdminAPIURL = srv.URL
+
+	centers, err := newAdminAPICenterProvider(cfg).CentersForCompany(context.Background(), "acme")
+	if err != nil || len(centers) != 1 || centers[0].ID != "C1" || *centers[0].Capacity != 3 {
+		t.Errorf("centers = %+v, %v; want C1 with capacity 3", centers, err)
+	}
+}
+
+func TestAdminAPIWakeUp(t *testing.T) {
+	var heads, gets atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+			return
+		}
+		gets.Add(1)
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	for _, wakeUp := range []bool{false, true} {
+		heads.Store(0)
+		gets.Store(0)
+		cfg := testConfig(t)
+		cfg.AdminAPIURL = srv.URL
+		cfg.AdminAPIWakeUp = wakeUp
+		provider := newAdminAPICenterProvider(cfg)
+
+		for i := 0; i < 2; i++ {
+			if _, err := provider.CentersForCompany(context.Background(), "acme"); err != nil {
+				t.Fatalf("wake-up %v: %v", wakeUp, err)
+			}
+		}
+		want := int32(0)
+		if wakeUp {
+			want = 1 // only before the first read; the second finds it awake
+		}
+		if heads.Load() != want || gets.Load() != 2 {
+			t.Errorf("wake-up %v: %d HEADs and %d GETs, want %d and 2", wakeUp, heads.Load(), gets.Load(), want)
+		}
+	}
+}
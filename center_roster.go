@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetCenterBookings is a center's roster from our own bookings: every
+// active booking assigned to it, earliest appointment first (?order=desc
+// reverses it), paginated. ?date=2024-05-01 keeps one UTC day. Unlike the
+// center's 'bookings' array upstream, this is what we think we assigned.
+func handleGetCenterBookings(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := parseListParams(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		list.SortField = "scheduledService.dateTime"
+		list.SortAsc = c.DefaultQuery("order", "asc") == "asc"
+
+		q := BookingQuery{CenterID: c.Param("centerId"), ActiveOnly: true, List: list}
+		if value := c.Query("date"); value != "" {
+			day, err := time.Parse(exportDateLayout, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "date must look like 2024-05-01"})
+				return
+			}
+			q.ScheduledFrom, q.ScheduledTo = day, day.AddDate(0, 0, 1)
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		bookings, total, err := store.FindBookings(ctx, q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch center bookings"})
+			return
+		}
+		setPaginationHeaders(c, total, list.Page)
+		c.JSON(http.StatusOK, bookings)
+	}
+}
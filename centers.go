@@ -0,0 +1,507 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sony/gobreaker"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// --- SERVICE CENTER LOOKUP ---
+
+// errCenterLookupBusy is returned when no lookup slot frees up within
+// centerLookupWait (or before the request deadline, if that comes first).
+var errCenterLookupBusy = errors.New("too many concurrent service center lookups")
+
+// errCenterSourceUnavailable is returned without querying while the center
+// circuit breaker is open.
+var errCenterSourceUnavailable = errors.New("service center source is unavailable")
+
+const defaultMaxExternalConcurrency = 5
+
+// centerLookupWait is how long a lookup queues for a free slot. It is well
+// under BOOKING_DEADLINE so plain throttling answers a quick 503 instead of
+// eating the whole deadline and turning into a 504.
+const centerLookupWait = 2 * time.Second
+
+// centerLookup guards queries to the center source. Its slots bound how many
+// run at once, so a burst of bookings cannot stampede the shared admin
+// cluster. Its breaker stops us hammering the source while it is down: after
+// enough consecutive failed lookups it opens and fails fast for a cooldown,
+// then lets a single probe through to check for recovery.
+type centerLookup struct {
+	slots   chan struct{}
+	wait    time.Duration // centerLookupWait
+	breaker *gobreaker.CircuitBreaker
+}
+
+func newCenterLookup(cfg Config) *centerLookup {
+	return &centerLookup{
+		slots:   make(chan struct{}, cfg.MaxExternalConcurrency),
+		wait:    centerLookupWait,
+		breaker: newCenterBreaker(cfg.CircuitFailureThreshold, cfg.CircuitCooldown),
+	}
+}
+
+func newCenterBreaker(failureThreshold int, cooldown time.Duration) *gobreaker.CircuitBreaker {
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "service-centers",
+		MaxRequests: 1,
+		Timeout:     cooldown,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= uint32(failureThreshold)
+		},
+		// Our own throttling says nothing about the health of the source.
+		IsSuccessful: func(err error) bool {
+			return err == nil || errors.Is(err, errCenterLookupBusy)
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			fmt.Printf("⚠️ Circuit %s: %s -> %s\n", name, from, to)
+		},
+	})
+}
+
+// activeCenters returns every active center.
+func (l *centerLookup) activeCenters(ctx context.Context) ([]ServiceCenterDBModel, error) {
+	centers, err := l.centers(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	active := centers[:0]
+	for _, center := range centers {
+		if center.IsActive {
+			active = append(active, center)
+		}
+	}
+	return active, nil
+}
+
+// centers returns the centers, active or not, that company can be booked
+// into. It fails fast while the circuit breaker is open, and otherwise waits
+// a short while for a free lookup slot.
+func (l *centerLookup) centers(ctx context.Context, company string) (centers []ServiceCenterDBModel, err error) {
+	if centerProvider == nil {
+		return nil, errCenterSourceUnavailable
+	}
+
+	ctx, span := tracer.Start(ctx, "fetchServiceCenters", trace.WithAttributes(attribute.String("company", company)))
+	defer func() {
+		span.SetAttributes(attribute.Int("centers.count", len(centers)), attribute.String("circuit.state", l.breaker.State().String()))
+		endSpanError(span, err)
+		span.End()
+	}()
+
+	result, err := l.breaker.Execute(func() (interface{}, error) {
+		return l.query(ctx, company)
+	})
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return nil, errCenterSourceUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.([]ServiceCenterDBModel), nil
+}
+
+func (l *centerLookup) query(ctx context.Context, company string) ([]ServiceCenterDBModel, error) {
+	wait := time.NewTimer(l.wait)
+	defer wait.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		defer func() { <-l.slots }()
+	case <-wait.C:
+		return nil, errCenterLookupBusy
+	case <-ctx.Done():
+		return nil, errCenterLookupBusy
+	}
+
+	start := time.Now()
+	defer func() { centerLookupStats.record(time.Since(start)) }()
+
+	return centerProvider.CentersForCompany(ctx, company)
+}
+
+// CenterAvailability is a center as shown to clients, with its remaining slots
+// (in the slot around a given time when SLOT_DURATION is set).
+// Unlimited centers report a capacity of 0 and should be read by the flag.
+type CenterAvailability struct {
+	ID          string `json:"centerId"`
+	Name        string `json:"name"`
+	Location    string `json:"location"`
+	Capacity    int    `json:"capacity"`
+	Unlimited   bool   `json:"unlimited,omitempty"`
+	BookedSlots int    `json:"bookedSlots"`
+	FreeSlots   int    `json:"freeSlots"`
+}
+
+// How a center without a 'capacity' field is treated, set from MISSING_CAPACITY.
+const (
+	MissingCapacityZero      = "zero"      // never bookable until the admin side sets one
+	MissingCapacityUnlimited = "unlimited" // always has room
+)
+
+// unlimitedSlots is what freeSlots reports for a center without a limit; it
+// sorts above any real center.
+const unlimitedSlots = math.MaxInt32
+
+// centerCapacity returns the center's capacity and whether it is limited at all.
+func (rules capacityRules) centerCapacity(center ServiceCenterDBModel) (int, bool) {
+	if center.Capacity == nil {
+		return 0, !rules.missingCapacityUnlimited
+	}
+	return int(*center.Capacity), true
+}
+
+// freeSlots is the room left in the slot starting at `at`; a zero `at` counts
+// every booking on the center.
+func (rules capacityRules) freeSlots(center ServiceCenterDBModel, at time.Time) int {
+	capacity, limited := rules.centerCapacity(center)
+	if !limited {
+		return unlimitedSlots
+	}
+	return capacity - rules.bookedSlotsAt(center, at)
+}
+
+func (rules capacityRules) centerAvailability(center ServiceCenterDBModel, at time.Time) CenterAvailability {
+	capacity, limited := rules.centerCapacity(center)
+	return CenterAvailability{
+		ID:          center.ID,
+		Name:        center.Name,
+		Location:    center.Location,
+		Capacity:    capacity,
+		Unlimited:   !limited,
+		BookedSlots: rules.bookedSlotsAt(center, at),
+		FreeSlots:   rules.freeSlots(center, at),
+	}
+}
+
+// centerDetails returns a center's display name and timezone, or
+// neither if it can't be found. Both are informational, so lookup failures
+// never block a booking.
+func (l *centerLookup) centerDetails(ctx context.Context, centerID string) ServiceCenterDBModel {
+	if serviceCenterCollection == nil {
+		centers, _ := l.centers(ctx, "")
+		for _, center := range centers {
+			if center.ID == centerID {
+				return center
+			}
+		}
+		return ServiceCenterDBModel{}
+	}
+	var center ServiceCenterDBModel
+	opts := options.FindOne().SetProjection(bson.M{"name": 1, "timezone": 1})
+	if err := serviceCenterCollection.FindOne(ctx, bson.M{"centerId": centerID}, opts).Decode(&center); err != nil {
+		return ServiceCenterDBModel{}
+	}
+	return center
+}
+
+// respondCenterLookupError maps a failed center lookup onto an HTTP response.
+func respondCenterLookupError(c *gin.Context, err error) {
+	if errors.Is(err, errCenterSourceUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service center source is unavailable, please retry later", "code": "UPSTREAM_UNAVAILABLE"})
+		return
+	}
+	var badResponse *upstreamBadResponseError
+	if errors.As(err, &badResponse) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Service center source sent a malformed response, please retry later", "code": "UPSTREAM_BAD_RESPONSE"})
+		return
+	}
+	if errors.Is(err, errCenterLookupBusy) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service center lookup is busy, please retry shortly", "code": "CENTER_LOOKUP_BUSY"})
+		return
+	}
+	fmt.Println("❌ Service center lookup failed:", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query service centers"})
+}
+
+// --- CENTER SELECTION ---
+
+// rankCenters orders the bookable centers from least to most busy in the slot
+// starting at `at`. Centers without an ID, switched off, without a positive
+// capacity, already at capacity or without a name are left out. Ties go to
+// the center earliest in preferred (the company's PREFERRED_CENTERS), then
+// keep the order the centers were returned in.
+func (rules capacityRules) rankCenters(centers []ServiceCenterDBModel, at time.Time, preferred []string) []*ServiceCenterDBModel {
+	ranked := []*ServiceCenterDBModel{}
+	for i := range centers {
+		if rules.centerExclusion(centers[i], at) != "" {
+			continue
+		}
+		ranked = append(ranked, &centers[i])
+	}
+	preference := func(center *ServiceCenterDBModel) int {
+		if i := slices.Index(preferred, center.ID); i >= 0 {
+			return i
+		}
+		return len(preferred)
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		busyA, busyB := rules.bookedSlotsAt(*ranked[a], at), rules.bookedSlotsAt(*ranked[b], at)
+		if busyA != busyB {
+			return busyA < busyB
+		}
+		return preference(ranked[a]) < preference(ranked[b])
+	})
+	return ranked
+}
+
+// Exclusion reason for a center that would have been a candidate but has no
+// name. Booking it would show customers a blank serviceCenterName.
+const excludedUnnamed = "missing name"
+
+// centerExclusion says why rankCenters leaves a center out, or "" if it is a
+// candidate.
+func (rules capacityRules) centerExclusion(center ServiceCenterDBModel, at time.Time) string {
+	switch {
+	case center.ID == "":
+		return "missing centerId"
+	case !center.IsActive:
+		return "inactive"
+	}
+	if capacity, limited := rules.centerCapacity(center); limited && capacity <= 0 {
+		return "no capacity"
+	}
+	if rules.freeSlots(center, at) <= 0 {
+		return "full"
+	}
+	if strings.TrimSpace(center.Name) == "" {
+		return excludedUnnamed
+	}
+	return ""
+}
+
+// selectBestCenter picks the least busy center with room, or nil if none qualify.
+func (rules capacityRules) selectBestCenter(centers []ServiceCenterDBModel, at time.Time, preferred []string) *ServiceCenterDBModel {
+	if ranked := rules.rankCenters(centers, at, preferred); len(ranked) > 0 {
+		return ranked[0]
+	}
+	return nil
+}
+
+// InactiveCenter identifies a center that exists but is switched off.
+type InactiveCenter struct {
+	ID       string `json:"centerId"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+}
+
+// respondNoCenterSelected explains why selection came back empty. "Nothing
+// registered" and "everything switched off" need different follow-ups from ops.
+func respondNoCenterSelected(c *gin.Context, rules capacityRules, centers []ServiceCenterDBModel, at time.Time) {
+	c.JSON(rules.noCenterSelected(centers, at))
+}
+
+// noCenterSelected is the status and body respondNoCenterSelected sends.
+func (rules capacityRules) noCenterSelected(centers []ServiceCenterDBModel, at time.Time) (int, gin.H) {
+	if len(centers) == 0 {
+		return http.StatusNotFound, gin.H{"error": "No service centers found", "code": "NO_CENTERS_FOUND"}
+	}
+
+	inactive := []InactiveCenter{}
+	for _, center := range centers {
+		if !center.IsActive {
+			inactive = append(inactive, InactiveCenter{ID: center.ID, Name: center.Name, Location: center.Location})
+		}
+	}
+	if len(inactive) == len(centers) {
+		return http.StatusNotFound, gin.H{
+			"error":           "All service centers are inactive",
+			"code":            "ALL_CENTERS_INACTIVE",
+			"inactiveCenters": inactive,
+		}
+	}
+
+	for _, center := range centers {
+		capacity, limited := rules.centerCapacity(center)
+		if center.ID != "" && center.IsActive && limited && capacity > 0 && rules.freeSlots(center, at) <= 0 {
+			return http.StatusConflict, gin.H{"error": "All active service centers are full", "code": "ALL_CENTERS_FULL"}
+		}
+	}
+
+	return http.StatusNotFound, gin.H{"error": "No valid service centers available", "code": "NO_VALID_CENTERS"}
+}
+
+// reserveCenterSlot adds booking to the center's 'bookings' array only if the
+// center is still active and below capacity, so the capacity check and the
+// write happen atomically. It reports false when the center filled up after
+// we last read it.
+func reserveCenterSlot(ctx context.Context, rules capacityRules, centerID string, booking DBBooking) (reserved bool, err error) {
+	if serviceCenterCollection == nil {
+		// No shared center state to reserve against (CENTERS_FILE without
+		// MongoDB); the capacity check during ranking is all we have.
+		return true, nil
+	}
+	ctx, span := tracer.Start(ctx, "reserveCenterSlot", trace.WithAttributes(attribute.String("center.id", centerID)))
+	defer func() {
+		span.SetAttributes(attribute.Bool("center.reserved", reserved))
+		endSpanError(span, err)
+		span.End()
+	}()
+
+	// An unparseable time just falls back to counting every booking.
+	at, _ := parseFlexibleTime(booking.ScheduledService.DateTime)
+	filter := bson.M{
+		"centerId":  centerID,
+		"is_active": true,
+		"$expr":     bson.M{"$lt": bson.A{rules.bookedSlotsExpr(at), rules.capacityExpr()}},
+	}
+	res, err := serviceCenterCollection.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"bookings": booking}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// capacityExpr is the aggregation counterpart of centerCapacity and
+// parseCapacity. Comparing against the raw field would let a string capacity
+// through every time, since BSON orders all numbers before strings.
+func (rules capacityRules) capacityExpr() bson.M {
+	missing := 0
+	if rules.missingCapacityUnlimited {
+		missing = unlimitedSlots
+	}
+	toLong := func(input interface{}) bson.M {
+		return bson.M{"$convert": bson.M{"input": input, "to": "long", "onError": 0, "onNull": 0}}
+	}
+	return bson.M{"$switch": bson.M{
+		"branches": bson.A{
+			bson.M{"case": bson.M{"$in": bson.A{bson.M{"$type": "$capacity"}, bson.A{"missing", "null"}}}, "then": missing},
+			bson.M{"case": bson.M{"$in": bson.A{bson.M{"$type": "$capacity"}, bson.A{"int", "long"}}}, "then": "$capacity"},
+			bson.M{"case": bson.M{"$eq": bson.A{bson.M{"$type": "$capacity"}, "double"}}, "then": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$capacity", bson.M{"$trunc": "$capacity"}}}, toLong("$capacity"), 0,
+			}}},
+			bson.M{"case": bson.M{"$eq": bson.A{bson.M{"$type": "$capacity"}, "string"}}, "then": toLong(bson.M{"$trim": bson.M{"input": "$capacity"}})},
+		},
+		"default": 0,
+	}}
+}
+
+// updateRemoteServiceCenter records booking on its center in 'auto_ai_db'
+// without a capacity check, for centers the client picked explicitly.
+func updateRemoteServiceCenter(ctx context.Context, booking DBBooking) error {
+	if serviceCenterCollection == nil {
+		return errCenterSourceUnavailable
+	}
+	return traced(ctx, "updateRemoteServiceCenter", func(ctx context.Context) error {
+		filter := bson.M{"centerId": booking.ScheduledService.ServiceCenterID}
+		update := bson.M{"$push": bson.M{"bookings": booking}}
+		_, err := serviceCenterCollection.UpdateOne(ctx, filter, update)
+		return err
+	})
+}
+
+// releaseCenterSlot removes a booking from its center's 'bookings' array in
+// 'auto_ai_db', undoing the push made when it was created.
+func releaseCenterSlot(ctx context.Context, booking DBBooking) error {
+	if serviceCenterCollection == nil {
+		return nil
+	}
+	return traced(ctx, "releaseCenterSlot", func(ctx context.Context) error {
+		filter := bson.M{"centerId": booking.ScheduledService.ServiceCenterID}
+		update := bson.M{"$pull": bson.M{"bookings": bson.M{
+			"vehicleId":                 booking.VehicleID,
+			"confirmationCode":          booking.ConfirmationCode,
+			"scheduledService.dateTime": booking.ScheduledService.DateTime,
+		}}}
+		_, err := serviceCenterCollection.UpdateOne(ctx, filter, update)
+		return err
+	})
+}
+
+// CenterBookings is a center's 'bookings' array. The admin side has written
+// it as null, left it out, or stored a non-array value; all of those count as
+// zero bookings instead of failing the decode of the whole center list.
+type CenterBookings []interface{}
+
+func (b *CenterBookings) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		*b = v
+	case nil:
+		*b = nil
+	default:
+		fmt.Printf("⚠️ Ignoring service center 'bookings' of JSON type %T; treating as empty\n", v)
+		*b = nil
+	}
+	return nil
+}
+
+func (b *CenterBookings) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.Array:
+		var items []interface{}
+		if err := (bson.RawValue{Type: t, Value: data}).Unmarshal(&items); err != nil {
+			return err
+		}
+		*b = items
+	case bsontype.Null, bsontype.Undefined:
+		*b = nil
+	default:
+		fmt.Printf("⚠️ Ignoring service center 'bookings' of type %s; treating as empty\n", t)
+		*b = nil
+	}
+	return nil
+}
+
+// CenterCapacity is a center's 'capacity'. The admin side sometimes sends it
+// as a string ("10") instead of a number. Both are accepted; anything else
+// is logged and read as 0 rather than failing the whole center list.
+type CenterCapacity int
+
+func (c *CenterCapacity) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*c = parseCapacity(value)
+	return nil
+}
+
+func (c *CenterCapacity) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	var value interface{}
+	if err := (bson.RawValue{Type: t, Value: data}).Unmarshal(&value); err != nil {
+		fmt.Printf("⚠️ Ignoring service center 'capacity' of type %s; treating as 0\n", t)
+		value = nil
+	}
+	*c = parseCapacity(value)
+	return nil
+}
+
+func parseCapacity(value interface{}) CenterCapacity {
+	switch v := value.(type) {
+	case int32:
+		return CenterCapacity(v)
+	case int64:
+		return CenterCapacity(v)
+	case float64:
+		if v == math.Trunc(v) {
+			return CenterCapacity(v)
+		}
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return CenterCapacity(n)
+		}
+	}
+	fmt.Printf("⚠️ Unparseable service center 'capacity' %v; treating as 0\n", value)
+	return 0
+}
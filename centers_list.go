@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetCenters lists the active centers with their free slots, ordered by
+// ?sort=freeSlots|name|capacity and ?order=asc|desc (default: most free first,
+// the same preference the booking heuristic has). With SLOT_DURATION set,
+// ?at= counts free slots around that time instead of across all bookings.
+func handleGetCenters(cfg Config, lookup *centerLookup) gin.HandlerFunc {
+	rules := cfg.capacityRules()
+	return func(c *gin.Context) {
+		sortBy := c.DefaultQuery("sort", "freeSlots")
+		order := c.DefaultQuery("order", "desc")
+
+		var less func(a, b CenterAvailability) bool
+		switch sortBy {
+		case "freeSlots":
+			less = func(a, b CenterAvailability) bool { return a.FreeSlots < b.FreeSlots }
+		case "name":
+			less = func(a, b CenterAvailability) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+		case "capacity":
+			less = func(a, b CenterAvailability) bool { return a.Capacity < b.Capacity }
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of: freeSlots, name, capacity"})
+			return
+		}
+		if order != "asc" && order != "desc" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+			return
+		}
+		var at time.Time
+		if value := c.Query("at"); value != "" {
+			var err error
+			if at, err = parseFlexibleTime(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid at: " + err.Error()})
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		centers, err := lookup.activeCenters(ctx)
+		if err != nil {
+			respondCenterLookupError(c, err)
+			return
+		}
+
+		availability := make([]CenterAvailability, 0, len(centers))
+		for _, center := range centers {
+			availability = append(availability, rules.centerAvailability(center, at))
+		}
+		sort.SliceStable(availability, func(i, j int) bool {
+			if order == "desc" {
+				return less(availability[j], availability[i])
+			}
+			return less(availability[i], availability[j])
+		})
+
+		c.JSON(http.StatusOK, availability)
+	}
+}
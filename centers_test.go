@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The admin side has written a center's bookings in several shapes; each
+// must decode, from Mongo or from an admin API, without losing the center.
+func TestCenterBookingsShapes(t *testing.T) {
+	cases := []struct {
+		name     string
+		bookings interface{}
+		missing  bool // leave the field out entirely
+		want     int
+	}{
+		{name: "array", bookings: []interface{}{map[string]interface{}{"vehicleId": "A_1"}, map[string]interface{}{"vehicleId": "B_2"}}, want: 2},
+		{name: "empty array", bookings: []interface{}{}, want: 0},
+		{name: "null", bookings: nil, want: 0},
+		{name: "missing", missing: true, want: 0},
+		{name: "object", bookings: map[string]interface{}{"vehicleId": "A_1"}, want: 0},
+		{name: "string", bookings: "none", want: 0},
+		{name: "number", bookings: 3, want: 0},
+	}
+	for _, tc := range cases {
+		doc := map[string]interface{}{"centerId": "C1", "name": "One", "is_active": true}
+		if !tc.missing {
+			doc["bookings"] = tc.bookings
+		}
+
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromMongo ServiceCenterDBModel
+		if err := bson.Unmarshal(raw, &fromMongo); err != nil {
+			t.Errorf("%s: BSON decode failed: %v", tc.name, err)
+		} else if len(fromMongo.Bookings) != tc.want || fromMongo.ID != "C1" {
+			t.Errorf("%s: BSON decoded %d bookings for %q, want %d for C1", tc.name, len(fromMongo.Bookings), fromMongo.ID, tc.want)
+		}
+
+		data, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromAPI ServiceCenterDBModel
+		if err := json.Unmarshal(data, &fromAPI); err != nil {
+			t.Errorf("%s: JSON decode failed: %v", tc.name, err)
+		} else if len(fromAPI.Bookings) != tc.want || fromAPI.ID != "C1" {
+			t.Errorf("%s: JSON decoded %d bookings for %q, want %d for C1", tc.name, len(fromAPI.Bookings), fromAPI.ID, tc.want)
+		}
+	}
+}
+
+// A center with unusable bookings still counts as a bookable, empty center.
+func TestOddBookingsCenterIsBookable(t *testing.T) {
+	center := testCenter("C1", 1, 0)
+	if err := json.Unmarshal([]byte(`{"vehicleId":"A_1"}`), &center.Bookings); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRouter(t, testConfig(t), center)
+
+	if w := serve(r, http.MethodPost, "/book-service", autoBooking); w.Code != http.StatusCreated {
+		t.Errorf("status = %d, body %s", w.Code, w.Body)
+	}
+}
+
+func TestNonPositiveCapacityIsSkipped(t *testing.T) {
+	zero, negative := testCenter("ZERO", 0, 0), testCenter("NEG", -3, 0)
+	missing := testCenter("MISSING", 0, 0)
+	missing.Capacity = nil
+	busy := testCenter("BUSY", 2, 1)
+	centers := []ServiceCenterDBModel{zero, negative, missing, busy}
+
+	for _, tc := range []struct {
+		rules capacityRules
+		want  string
+	}{
+		{rules: capacityRules{}, want: "BUSY"},
+		{rules: capacityRules{missingCapacityUnlimited: true}, want: "MISSING"},
+	} {
+		for _, center := range []ServiceCenterDBModel{zero, negative} {
+			if got := tc.rules.centerExclusion(center, time.Time{}); got != "no capacity" {
+				t.Errorf("%+v: %s excluded as %q, want \"no capacity\"", tc.rules, center.ID, got)
+			}
+		}
+		best := tc.rules.selectBestCenter(centers, time.Time{}, nil)
+		if best == nil || best.ID != tc.want {
+			t.Errorf("%+v: selected %v, want %s", tc.rules, best, tc.want)
+		}
+	}
+}
+
+func TestMissingCapacitySetting(t *testing.T) {
+	missing := testCenter("MISSING", 0, 0)
+	missing.Capacity = nil
+
+	for setting, wantStatus := range map[string]int{
+		MissingCapacityZero:      http.StatusNotFound, // NO_VALID_CENTERS
+		MissingCapacityUnlimited: http.StatusCreated,
+	} {
+		t.Run(setting, func(t *testing.T) {
+			t.Setenv("MISSING_CAPACITY", setting)
+			r := newTestRouter(t, testConfig(t), missing, testCenter("ZERO", 0, 0))
+
+			if w := serve(r, http.MethodPost, "/book-service", autoBooking); w.Code != wantStatus {
+				t.Errorf("status = %d, want %d; body %s", w.Code, wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestCenterCapacityShapes(t *testing.T) {
+	cases := []struct {
+		name     string
+		capacity interface{}
+		missing  bool
+		want     *int // nil: no capacity on the center
+	}{
+		{name: "number", capacity: 10, want: ptr(10)},
+		{name: "string", capacity: "10", want: ptr(10)},
+		{name: "padded string", capacity: " 7 ", want: ptr(7)},
+		{name: "whole float", capacity: 4.0, want: ptr(4)},
+		{name: "fractional float", capacity: 4.5, want: ptr(0)},
+		{name: "unparseable string", capacity: "ten", want: ptr(0)},
+		{name: "object", capacity: map[string]interface{}{"max": 3}, want: ptr(0)},
+		{name: "null", capacity: nil, want: nil},
+		{name: "missing", missing: true, want: nil},
+	}
+	for _, tc := range cases {
+		doc := map[string]interface{}{"centerId": "C1"}
+		if !tc.missing {
+			doc["capacity"] = tc.capacity
+		}
+		check := func(source string, center ServiceCenterDBModel) {
+			switch {
+			case tc.want == nil && center.Capacity != nil:
+				t.Errorf("%s %s: capacity = %d, want none", source, tc.name, *center.Capacity)
+			case tc.want != nil && (center.Capacity == nil || int(*center.Capacity) != *tc.want):
+				t.Errorf("%s %s: capacity = %v, want %d", source, tc.name, center.Capacity, *tc.want)
+			}
+		}
+
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromMongo ServiceCenterDBModel
+		if err := bson.Unmarshal(raw, &fromMongo); err != nil {
+			t.Errorf("BSON %s: decode failed: %v", tc.name, err)
+		} else {
+			check("BSON", fromMongo)
+		}
+
+		data, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromAPI ServiceCenterDBModel
+		if err := json.Unmarshal(data, &fromAPI); err != nil {
+			t.Errorf("JSON %s: decode failed: %v", tc.name, err)
+		} else {
+			check("JSON", fromAPI)
+		}
+	}
+}
+
+func ptr(n int) *int { return &n }
+
+func TestStringCapacityCenterFills(t *testing.T) {
+	var center ServiceCenterDBModel
+	err := json.Unmarshal([]byte(`{"centerId":"C1","name":"One","is_active":true,"capacity":"2",
+		"bookings":[{"vehicleId":"A_1"},{"vehicleId":"B_2"}]}`), &center)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRouter(t, testConfig(t), center)
+
+	w := serve(r, http.MethodPost, "/book-service", autoBooking)
+	var body struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &body)
+	if w.Code != http.StatusConflict || body.Code != "ALL_CENTERS_FULL" {
+		t.Errorf("status = %d, code = %q, want 409 ALL_CENTERS_FULL", w.Code, body.Code)
+	}
+}
+
+func TestBookServiceLookupBusy(t *testing.T) {
+	t.Setenv("WAITLIST_ON_DEADLINE", "true")
+	cfg := testConfig(t)
+	cfg.MaxExternalConcurrency = 1
+	cfg.BookingDeadline = 50 * time.Millisecond
+	d := newDeps(cfg)
+	d.lookup.wait = 10 * time.Millisecond
+	d.lookup.slots <- struct{}{}                  // every lookup slot is taken
+	newTestRouter(t, cfg, testCenter("C1", 5, 0)) // for its store and centers; d replaces its deps
+	r := newRouter(cfg, d)
+
+	w := serve(r, http.MethodPost, "/book-service", autoBooking)
+	var body struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &body)
+	if w.Code != http.StatusServiceUnavailable || body.Code != "CENTER_LOOKUP_BUSY" {
+		t.Fatalf("status = %d, body %s, want 503 CENTER_LOOKUP_BUSY", w.Code, w.Body)
+	}
+
+	if w := serve(r, http.MethodGet, "/bookings", ""); strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("a throttled booking was waitlisted: %s", w.Body)
+	}
+	letters, _, err := store.FindDeadLetters(context.Background(), ListParams{})
+	if err != nil || len(letters) != 0 {
+		t.Errorf("dead letters = %v, %v; want none for a throttled booking", letters, err)
+	}
+}
+
+// Legacy entries hold every format parseFlexibleTime reads; each must land
+// in the slot it names, not where its raw string happens to sort.
+func TestBookedSlotsAtLegacyTimes(t *testing.T) {
+	at := time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)
+	rules := capacityRules{slotDuration: time.Hour}
+	entry := func(dateTime string) map[string]interface{} {
+		return map[string]interface{}{"scheduledService": map[string]interface{}{"dateTime": dateTime}}
+	}
+
+	inside := []string{
+		"2030-01-01T10:30:00Z",
+		"2030-01-01T10:30:00",       // no zone: UTC
+		"2030-01-01T15:45:00+05:30", // 10:15Z
+		" 2030-01-01T09:30:00.5Z ",
+		strconv.FormatInt(at.Unix(), 10),
+		strconv.FormatInt(at.Add(20*time.Minute).UnixMilli(), 10),
+		"next tuesday", // unreadable, so it blocks every slot
+	}
+	outside := []string{
+		"2030-01-01T11:00:00Z",
+		"2030-01-01T10:30:00+05:00", // 05:30Z, though it sorts inside the window
+		"2030-01-01T20:00:00",
+		strconv.FormatInt(at.Add(-2*time.Hour).Unix(), 10),
+	}
+	for _, dateTime := range inside {
+		center := ServiceCenterDBModel{Bookings: CenterBookings{entry(dateTime)}}
+		if n := rules.bookedSlotsAt(center, at); n != 1 {
+			t.Errorf("%q: booked %d, want it in the 10:00Z slot", dateTime, n)
+		}
+	}
+	for _, dateTime := range outside {
+		center := ServiceCenterDBModel{Bookings: CenterBookings{entry(dateTime)}}
+		if n := rules.bookedSlotsAt(center, at); n != 0 {
+			t.Errorf("%q: booked %d, want it outside the 10:00Z slot", dateTime, n)
+		}
+	}
+}
+
+// flexibleTimeExpr only hands $dateFromString and $toDate the strings
+// parseFlexibleTime reads, so Mongo and Go agree on which entries have a time.
+func TestFlexibleTimePatternsMatchParser(t *testing.T) {
+	patterns := []*regexp.Regexp{
+		regexp.MustCompile(epochSecondsPattern),
+		regexp.MustCompile(epochMillisPattern),
+		regexp.MustCompile(rfc3339Pattern),
+	}
+	for _, value := range []string{
+		"2030-01-01T10:00:00Z",
+		"2030-01-01T10:00:00.123Z",
+		"2030-01-01T10:00:00+05:30",
+		"2030-01-01T10:00:00",
+		"1893492000",
+		"1893492000000",
+		"0",
+		"2030-01-01",
+		"2030-01-01 10:00:00",
+		"01/01/2030 10:00",
+		"1893492000.5",
+		"-1893492000",
+		"next tuesday",
+	} {
+		_, err := parseFlexibleTime(value)
+		matched := false
+		for _, pattern := range patterns {
+			matched = matched || pattern.MatchString(value)
+		}
+		if matched != (err == nil) {
+			t.Errorf("%q: pattern match %v, parseFlexibleTime error %v", value, matched, err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Vehicle IDs are issued as <COMPANY>_<NUMBER>, e.g. PQR_999.
+const companyDelimiter = "_"
+
+// Company names are short codes; anything else (slashes, spaces, dots) is a
+// malformed or hostile vehicle ID.
+var validCompanyName = regexp.MustCompile(`^[A-Za-z0-9-]{1,32}$`)
+
+// companyVehicleFilter matches every booking whose vehicleId belongs to company.
+func companyVehicleFilter(company string) bson.M {
+	return bson.M{"vehicleId": bson.M{"$regex": "^" + regexp.QuoteMeta(company+companyDelimiter)}}
+}
+
+// extractCompanyName returns the company part of a vehicle ID ("PQR_999" ->
+// "PQR"). An ID without the delimiter is treated as the company itself; one
+// that starts with the delimiter ("_123", "___") has no company and is invalid.
+func extractCompanyName(vehicleID string) (string, error) {
+	company, _, _ := strings.Cut(strings.TrimSpace(vehicleID), companyDelimiter)
+	if company == "" {
+		return "", fmt.Errorf("vehicleId %q has no company prefix before %q", vehicleID, companyDelimiter)
+	}
+	return company, nil
+}
+
+// validateCompanyName rejects company names with characters that could break
+// or escape the queries and paths they are interpolated into.
+func validateCompanyName(company string) error {
+	if !validCompanyName.MatchString(company) {
+		return fmt.Errorf("company %q must be 1-32 letters, digits or '-'", company)
+	}
+	return nil
+}
+
+// companyAllowed reports whether bookings for company are accepted. An empty
+// allowlist accepts everyone.
+func companyAllowed(cfg Config, company string) bool {
+	if len(cfg.AllowedCompanies) == 0 {
+		return true
+	}
+	for _, allowed := range cfg.AllowedCompanies {
+		if strings.EqualFold(allowed, company) {
+			return true
+		}
+	}
+	return false
+}
+
+// VehicleIDProblem is why a vehicle ID can't be booked, with the response
+// status and code /book-service answers it with.
+type VehicleIDProblem struct {
+	Status int
+	Code   string
+	Reason string
+}
+
+// checkVehicleID runs every vehicle ID check that needs no database: the
+// company prefix, its format and the company allowlist.
+func checkVehicleID(cfg Config, vehicleID string) (string, *VehicleIDProblem) {
+	company, err := extractCompanyName(vehicleID)
+	if err != nil {
+		return "", &VehicleIDProblem{http.StatusBadRequest, "INVALID_VEHICLE_ID", err.Error()}
+	}
+	if err := validateCompanyName(company); err != nil {
+		return company, &VehicleIDProblem{http.StatusBadRequest, "INVALID_COMPANY", "Invalid vehicleId: " + err.Error()}
+	}
+	if !companyAllowed(cfg, company) {
+		return company, &VehicleIDProblem{http.StatusForbidden, "COMPANY_NOT_ALLOWED", "Company " + company + " is not allowed to book"}
+	}
+	return company, nil
+}
+
+// handleValidateVehicle lets forms check a vehicle ID as it is typed, using
+// the same checks as /book-service without touching the database.
+func handleValidateVehicle(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		company, problem := checkVehicleID(cfg, c.Query("id"))
+		if problem != nil {
+			c.JSON(http.StatusOK, gin.H{"valid": false, "reason": problem.Reason, "code": problem.Code})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"valid": true, "company": company})
+	}
+}
+
+// requiresSync reports whether company treats the center side as the source
+// of truth, so a booking that can't be pushed there has not succeeded.
+func requiresSync(cfg Config, company string) bool {
+	for _, strict := range cfg.RequireSyncCompanies {
+		if strings.EqualFold(strict, company) {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackCenter returns the overflow center configured for company, if any.
+func fallbackCenter(cfg Config, company string) (string, bool) {
+	centerID, ok := cfg.FallbackCenters[strings.ToLower(company)]
+	return centerID, ok
+}
+
+// preferredCenters is company's PREFERRED_CENTERS list, most preferred first.
+func preferredCenters(cfg Config, company string) []string {
+	return cfg.PreferredCenters[strings.ToLower(company)]
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestValidateCompanyName(t *testing.T) {
+	valid := []string{"PQR", "acme", "acme-eu", "A1", strings.Repeat("a", 32)}
+	for _, company := range valid {
+		if err := validateCompanyName(company); err != nil {
+			t.Errorf("validateCompanyName(%q) = %v, want nil", company, err)
+		}
+	}
+
+	adversarial := []string{
+		"",
+		"../admin",
+		"acme/centers",
+		"acme%2Fcenters",
+		"acme corp",
+		"acme.eu",
+		".*",
+		"^acme|globex",
+		"$where",
+		`{"$ne":null}`,
+		"acme\x00",
+		"acme\n",
+		"ａｃｍｅ", // fullwidth letters
+		strings.Repeat("a", 33),
+	}
+	for _, company := range adversarial {
+		if err := validateCompanyName(company); err == nil {
+			t.Errorf("validateCompanyName(%q) = nil, want an error", company)
+		}
+	}
+}
+
+func TestBookServiceRejectsHostileCompany(t *testing.T) {
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 5, 0))
+
+	for _, vehicleID := range []string{"../admin_1", "acme/x_1", ".*_1", "a b_1"} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"vehicleId":        vehicleID,
+			"scheduledService": map[string]interface{}{"isScheduled": true, "dateTime": "2030-01-01T10:00:00Z"},
+		})
+		w := serve(r, http.MethodPost, "/book-service", string(body))
+		var resp struct {
+			Code string `json:"code"`
+		}
+		decode(t, w, &resp)
+		if w.Code != http.StatusBadRequest || resp.Code != "INVALID_COMPANY" {
+			t.Errorf("%q: status = %d, code = %q, want 400 INVALID_COMPANY", vehicleID, w.Code, resp.Code)
+		}
+
+		w = serve(r, http.MethodGet, "/validate-vehicle?id="+url.QueryEscape(vehicleID), "")
+		var check struct {
+			Valid bool   `json:"valid"`
+			Code  string `json:"code"`
+		}
+		decode(t, w, &check)
+		if check.Valid || check.Code != "INVALID_COMPANY" {
+			t.Errorf("/validate-vehicle %q = %+v, want INVALID_COMPANY", vehicleID, check)
+		}
+	}
+
+	if w := serve(r, http.MethodGet, "/bookings", ""); strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("rejected bookings were saved: %s", w.Body)
+	}
+}
+
+func TestExtractCompanyName(t *testing.T) {
+	cases := []struct {
+		vehicleID, want string
+		wantErr         bool
+	}{
+		{vehicleID: "PQR_999", want: "PQR"},
+		{vehicleID: "PQR_999_B", want: "PQR"},
+		{vehicleID: "PQR", want: "PQR"}, // no delimiter: the whole ID is the company
+		{vehicleID: " PQR_1 ", want: "PQR"},
+		{vehicleID: "_123", wantErr: true},
+		{vehicleID: "_", wantErr: true},
+		{vehicleID: "___", wantErr: true},
+		{vehicleID: "", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := extractCompanyName(tc.vehicleID)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("extractCompanyName(%q) = %q, %v; want %q, error %v", tc.vehicleID, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestBookServiceRejectsEmptyCompanyPrefix(t *testing.T) {
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 5, 0))
+
+	for _, vehicleID := range []string{"_123", "___"} {
+		w := serve(r, http.MethodPost, "/book-service", `{"vehicleId":"`+vehicleID+`"}`)
+		var resp struct {
+			Code string `json:"code"`
+		}
+		decode(t, w, &resp)
+		if w.Code != http.StatusBadRequest || resp.Code != "INVALID_VEHICLE_ID" {
+			t.Errorf("%q: status = %d, code = %q, want 400 INVALID_VEHICLE_ID", vehicleID, w.Code, resp.Code)
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// --- SERVICE CONFIGURATION ---
+
+const defaultDBName = "techathon_db"
+
+// Config is everything the service reads from the environment. It is loaded
+// once in main and passed to whatever needs it.
+type Config struct {
+	Store       string // StoreMongo or StoreMemory
+	CentersFile string // JSON list of centers to serve instead of 'auto_ai_db'; STORE=memory only
+	MongoURI    string
+	DBName      string
+	Port        string
+	BindAddr    string // interface to listen on, e.g. 127.0.0.1; empty listens on all
+	AdminAPIURL string // admin API for companies without an ADMIN_API_ROUTES entry
+	AdminToken  string // bearer token for ops endpoints; empty disables them
+
+	CenterSource   string              // CenterSourceMongo, or CenterSourceAdminAPI (STORE=memory only)
+	AdminAPIRoutes map[string][]string // company -> base URLs of the admin APIs of its regions
+	AdminAPIWakeUp bool                // HEAD an admin API that may be asleep before reading from it
+
+	CORSAllowedOrigins   []string      // empty allows every origin
+	CORSAllowCredentials bool          // requires CORSAllowedOrigins
+	CORSMaxAge           time.Duration // how long browsers may cache a preflight answer
+
+	OTLPEndpoint string // OTLP/HTTP collector URL for traces; empty disables export
+
+	RequestTimeout    time.Duration // deadline for a handler's DB work
+	CenterSyncTimeout time.Duration // background push to 'auto_ai_db'
+	DisableRemoteSync bool          // never push bookings to 'auto_ai_db', auto-assigned ones included (backfills)
+	// RequireSyncCompanies push client-chosen bookings to 'auto_ai_db' before
+	// answering and fail the booking if that push fails.
+	RequireSyncCompanies []string
+	MongoConnectTimeout  time.Duration
+	LogExportTimeout     time.Duration // deadline for streaming one GET /logs/export
+	BookingDeadline      time.Duration // end-to-end limit for one POST /book-service
+	WaitlistOnDeadline   bool          // save a booking past BookingDeadline as PENDING instead of answering 504
+
+	MaxExternalConcurrency int
+	MaxInFlight            int // requests served at once before new ones get 503
+	MongoWriteRetries      int
+	MaxFieldLength         int // longest string accepted in a booking or log field
+
+	ResponseEnvelope bool // wrap JSON responses as {"data","error","meta"} unless ?envelope=false
+
+	IDFormat string // IDFormatULID, IDFormatUUID or IDFormatLegacy, for generated IDs
+
+	CircuitFailureThreshold int           // consecutive failed center lookups before the breaker opens
+	CircuitCooldown         time.Duration // how long the breaker stays open before probing
+
+	MaxCenterAttempts int // ranked centers tried when the best fills up mid-booking
+
+	MissingCapacity string        // MissingCapacityZero or MissingCapacityUnlimited
+	SlotDuration    time.Duration // 0 treats capacity as a total rather than per time slot
+
+	HealthHistorySize   int // /system-status evaluations kept for /system-status/history
+	BookingHistoryLimit int // change events kept on each booking
+
+	ColdLookupThreshold time.Duration // center lookups slower than this report "cold"
+	WarmUpCenterLookup  bool          // run one throwaway center lookup at startup
+	StartupSelfTest     bool          // check center documents still have the expected shape at startup
+	SelfTestCenterID    string        // center the self-test reads; empty samples any
+
+	AllowedCompanies []string          // empty means every company may book
+	FallbackCenters  map[string]string // lowercased company -> overflow centerId
+	// PreferredCenters breaks ties between equally busy centers: lowercased
+	// company -> centerIds, most preferred first.
+	PreferredCenters map[string][]string
+
+	ConfirmationCodeFormats  map[string]*regexp.Regexp // lowercased company -> pattern client codes must match
+	ConfirmationCodePrefixes map[string]string         // lowercased company -> prefix of codes issued for it
+
+	// SingleActiveBookingPerVehicle answers a repeat booking for a vehicle
+	// that is already scheduled with 409 instead of 200 "already booked".
+	SingleActiveBookingPerVehicle bool
+
+	PastBookingGrace time.Duration // how far in the past a scheduled time may be before it is rejected
+
+	DedupWindow        time.Duration // a repeat booking within this window returns the first; 0 disables
+	DedupTimeTolerance time.Duration // how far apart two scheduled times can be and still count as the same
+
+	PendingTTL           time.Duration // PENDING bookings older than this expire; 0 disables the sweeper
+	PendingSweepInterval time.Duration
+
+	ReminderLead          time.Duration // REMINDER_DUE goes out this long before the appointment; 0 disables reminders
+	ReminderSweepInterval time.Duration
+	NotifyWebhookURL      string // receives notification events as JSON; empty only logs them
+
+	LogDuplicatePolicy    string        // LogDuplicateReject, LogDuplicateIgnore or LogDuplicateOverwrite
+	LogContentDedupWindow time.Duration // POST /logs drops an event identical to one this recent; 0 disables
+
+	// WriteConcern for the Bookings and Logs collections: "majority" or a node
+	// count such as "1". Empty keeps the driver default. "majority" survives a
+	// failover but every booking write waits for a majority of replicas, which
+	// adds a few ms on Atlas and more when a secondary lags.
+	WriteConcern string
+}
+
+// loadConfig reads the environment, applies defaults and exits with a list of
+// every missing or malformed variable.
+func loadConfig() Config {
+	env := &envReader{}
+	cfg := Config{
+		Store:       strings.ToLower(env.str("STORE", StoreMongo)),
+		CentersFile: env.str("CENTERS_FILE", ""),
+		DBName:      env.str("DB_NAME", defaultDBName),
+		Port:        env.str("PORT", "8080"),
+		BindAddr:    strings.Trim(env.str("BIND_ADDR", ""), "[]"),
+		AdminAPIURL: env.str("ADMIN_API_URL", ExternalAPIBase),
+		AdminToken:  env.str("ADMIN_TOKEN", ""),
+
+		CenterSource:   strings.ToLower(env.str("CENTER_SOURCE", CenterSourceMongo)),
+		AdminAPIRoutes: env.jsonListMapping("ADMIN_API_ROUTES", "ADMIN_API_ROUTES_FILE"),
+		AdminAPIWakeUp: env.boolean("ADMIN_API_WAKE_UP", false),
+
+		CORSAllowedOrigins:   env.list("CORS_ALLOWED_ORIGINS"),
+		CORSAllowCredentials: env.boolean("CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           env.optionalDuration("CORS_MAX_AGE", 12*time.Hour),
+
+		OTLPEndpoint: env.str("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+
+		RequestTimeout:       env.duration("REQUEST_TIMEOUT", 10*time.Second),
+		CenterSyncTimeout:    env.duration("CENTER_SYNC_TIMEOUT", 5*time.Second),
+		MongoConnectTimeout:  env.duration("MONGO_CONNECT_TIMEOUT", 10*time.Second),
+		LogExportTimeout:     env.duration("LOG_EXPORT_TIMEOUT", 5*time.Minute),
+		BookingDeadline:      env.duration("BOOKING_DEADLINE", 10*time.Second),
+		WaitlistOnDeadline:   env.boolean("WAITLIST_ON_DEADLINE", false),
+		DisableRemoteSync:    env.boolean("DISABLE_REMOTE_SYNC", false),
+		RequireSyncCompanies: env.list("REQUIRE_SYNC_COMPANIES"),
+
+		MaxExternalConcurrency: env.positiveInt("MAX_EXTERNAL_CONCURRENCY", defaultMaxExternalConcurrency),
+		MaxInFlight:            env.positiveInt("MAX_IN_FLIGHT", defaultMaxInFlight),
+		MongoWriteRetries:      env.positiveInt("MONGO_WRITE_RETRIES", defaultWriteRetryAttempts),
+		MaxFieldLength:         env.positiveInt("MAX_FIELD_LENGTH", defaultMaxFieldLength),
+
+		ResponseEnvelope: env.boolean("RESPONSE_ENVELOPE", false),
+
+		IDFormat: strings.ToLower(env.str("ID_FORMAT", IDFormatULID)),
+
+		CircuitFailureThreshold: env.positiveInt("CIRCUIT_FAILURE_THRESHOLD", 5),
+		CircuitCooldown:         env.duration("CIRCUIT_COOLDOWN", 30*time.Second),
+
+		MaxCenterAttempts: env.positiveInt("MAX_CENTER_ATTEMPTS", 3),
+
+		MissingCapacity: strings.ToLower(env.str("MISSING_CAPACITY", MissingCapacityZero)),
+		SlotDuration:    env.duration("SLOT_DURATION", 0),
+
+		HealthHistorySize:   env.positiveInt("HEALTH_HISTORY_SIZE", defaultHealthHistorySize),
+		BookingHistoryLimit: env.positiveInt("BOOKING_HISTORY_LIMIT", defaultBookingHistoryLimit),
+
+		ColdLookupThreshold: env.duration("COLD_LOOKUP_THRESHOLD", 3*time.Second),
+		WarmUpCenterLookup:  env.boolean("WARM_UP_CENTER_LOOKUP", false),
+		StartupSelfTest:     env.boolean("STARTUP_SELFTEST", false),
+		SelfTestCenterID:    env.str("SELFTEST_CENTER_ID", ""),
+
+		AllowedCompanies: env.list("ALLOWED_COMPANIES"),
+		FallbackCenters:  env.mapping("FALLBACK_CENTERS"),
+		PreferredCenters: env.listMapping("PREFERRED_CENTERS"),
+
+		ConfirmationCodeFormats:  env.patterns("CONFIRMATION_CODE_FORMATS"),
+		ConfirmationCodePrefixes: env.mapping("CONFIRMATION_CODE_PREFIXES"),
+
+		SingleActiveBookingPerVehicle: env.boolean("SINGLE_ACTIVE_BOOKING_PER_VEHICLE", false),
+
+		PastBookingGrace: env.optionalDuration("PAST_BOOKING_GRACE", 5*time.Minute),
+
+		DedupWindow:        env.optionalDuration("DEDUP_WINDOW", 0),
+		DedupTimeTolerance: env.duration("DEDUP_TIME_TOLERANCE", time.Minute),
+
+		PendingTTL:           env.optionalDuration("PENDING_TTL", 0),
+		PendingSweepInterval: env.duration("PENDING_SWEEP_INTERVAL", time.Minute),
+
+		ReminderLead:          env.optionalDuration("REMINDER_LEAD", 0),
+		ReminderSweepInterval: env.duration("REMINDER_SWEEP_INTERVAL", time.Minute),
+		NotifyWebhookURL:      env.str("NOTIFY_WEBHOOK_URL", ""),
+
+		LogDuplicatePolicy:    strings.ToLower(env.str("LOG_DUPLICATE_POLICY", LogDuplicateReject)),
+		LogContentDedupWindow: env.optionalDuration("LOG_CONTENT_DEDUP_WINDOW", 0),
+
+		WriteConcern: env.str("WRITE_CONCERN", ""),
+	}
+	switch cfg.Store {
+	case StoreMongo:
+		cfg.MongoURI = env.required("MONGO_URI")
+	case StoreMemory:
+		// Nothing to connect to.
+	default:
+		env.problems = append(env.problems, fmt.Sprintf("STORE must be %q or %q, got %q", StoreMongo, StoreMemory, cfg.Store))
+	}
+	if cfg.MissingCapacity != MissingCapacityZero && cfg.MissingCapacity != MissingCapacityUnlimited {
+		env.problems = append(env.problems, fmt.Sprintf("MISSING_CAPACITY must be %q or %q, got %q", MissingCapacityZero, MissingCapacityUnlimited, cfg.MissingCapacity))
+	}
+	switch cfg.IDFormat {
+	case IDFormatULID, IDFormatUUID, IDFormatLegacy:
+	default:
+		env.problems = append(env.problems, fmt.Sprintf("ID_FORMAT must be ulid, uuid or legacy, got %q", cfg.IDFormat))
+	}
+	switch cfg.LogDuplicatePolicy {
+	case LogDuplicateReject, LogDuplicateIgnore, LogDuplicateOverwrite:
+	default:
+		env.problems = append(env.problems, fmt.Sprintf("LOG_DUPLICATE_POLICY must be reject, ignore or overwrite, got %q", cfg.LogDuplicatePolicy))
+	}
+	if cfg.CORSAllowCredentials && len(cfg.CORSAllowedOrigins) == 0 {
+		env.problems = append(env.problems, "CORS_ALLOW_CREDENTIALS needs CORS_ALLOWED_ORIGINS; browsers reject credentials for any origin")
+	}
+	if err := corsConfig(cfg).Validate(); err != nil {
+		env.problems = append(env.problems, "CORS_ALLOWED_ORIGINS: "+err.Error())
+	}
+	// Centers read from a file can't be reserved or synced against: those
+	// writes go to 'auto_ai_db', which would then disagree with the file.
+	if cfg.CentersFile != "" && cfg.Store == StoreMongo {
+		env.problems = append(env.problems, "CENTERS_FILE needs STORE=memory; with STORE=mongo, slot reservations and syncs would still go to 'auto_ai_db'")
+	}
+	switch cfg.CenterSource {
+	case CenterSourceMongo:
+	case CenterSourceAdminAPI:
+		if cfg.CentersFile != "" {
+			env.problems = append(env.problems, "CENTERS_FILE and CENTER_SOURCE=admin-api both set where centers come from; pick one")
+		}
+		// Like CENTERS_FILE: reservations, center details and syncs look
+		// centers up in 'auto_ai_db', where regional centers don't exist.
+		if cfg.Store == StoreMongo {
+			env.problems = append(env.problems, "CENTER_SOURCE=admin-api needs STORE=memory; with STORE=mongo, slot reservations and syncs would look for the regional centers in 'auto_ai_db' and fail")
+		}
+	default:
+		env.problems = append(env.problems, fmt.Sprintf("CENTER_SOURCE must be %q or %q, got %q", CenterSourceMongo, CenterSourceAdminAPI, cfg.CenterSource))
+	}
+	for company, baseURLs := range cfg.AdminAPIRoutes {
+		for _, baseURL := range baseURLs {
+			if !isHTTPURL(baseURL) {
+				env.problems = append(env.problems, fmt.Sprintf("ADMIN_API_ROUTES has an invalid URL for %q: %q", company, baseURL))
+			}
+		}
+	}
+	if !isHTTPURL(cfg.AdminAPIURL) {
+		env.problems = append(env.problems, fmt.Sprintf("ADMIN_API_URL must be an http(s) URL, got %q", cfg.AdminAPIURL))
+	}
+	if _, _, err := net.SplitHostPort(cfg.BindAddr); err == nil {
+		env.problems = append(env.problems, fmt.Sprintf("BIND_ADDR must be a host or IP without a port (set the port with PORT), got %q", cfg.BindAddr))
+	}
+	if _, err := parseWriteConcern(cfg.WriteConcern); err != nil {
+		env.problems = append(env.problems, err.Error())
+	}
+
+	// Falling back to the demo database in production once went unnoticed for
+	// days, so the fallback is loud and can be turned into a hard failure.
+	if cfg.Store == StoreMongo && strings.TrimSpace(os.Getenv("DB_NAME")) == "" {
+		if env.boolean("REQUIRE_DB_NAME", false) {
+			env.problems = append(env.problems, "DB_NAME is not set (required because REQUIRE_DB_NAME=true)")
+		} else {
+			fmt.Printf("⚠️ WARNING: DB_NAME is not set, falling back to default database %q\n", defaultDBName)
+		}
+	}
+
+	if len(env.problems) > 0 {
+		log.Fatalf("Invalid configuration:\n  - %s", strings.Join(env.problems, "\n  - "))
+	}
+	return cfg
+}
+
+// isHTTPURL reports whether value is an absolute http or https URL.
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// listenAddr is where the server binds: BIND_ADDR (all interfaces when
+// empty) on PORT.
+func (cfg Config) listenAddr() string {
+	return net.JoinHostPort(cfg.BindAddr, cfg.Port)
+}
+
+// parseWriteConcern turns WRITE_CONCERN into a driver write concern; nil means
+// the driver default.
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return nil, nil
+	case "majority":
+		return writeconcern.Majority(), nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("WRITE_CONCERN must be \"majority\" or a node count, got %q", value)
+	}
+	return &writeconcern.WriteConcern{W: n}, nil
+}
+
+// envReader collects every problem instead of stopping at the first, so a
+// misconfigured deploy shows the whole list in one go.
+type envReader struct {
+	problems []string
+}
+
+func (e *envReader) str(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+func (e *envReader) required(key string) string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		e.problems = append(e.problems, key+" is not set")
+	}
+	return v
+}
+
+func (e *envReader) positiveInt(key string, def int) int {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		e.problems = append(e.problems, fmt.Sprintf("%s must be a positive integer, got %q", key, v))
+		return def
+	}
+	return n
+}
+
+func (e *envReader) duration(key string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		e.problems = append(e.problems, fmt.Sprintf("%s must be a positive duration like 10s, got %q", key, v))
+		return def
+	}
+	return d
+}
+
+// optionalDuration is duration for features where 0 means "off".
+func (e *envReader) optionalDuration(key string, def time.Duration) time.Duration {
+	if strings.TrimSpace(os.Getenv(key)) == "0" {
+		return 0
+	}
+	return e.duration(key, def)
+}
+
+func (e *envReader) boolean(key string, def bool) bool {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.problems = append(e.problems, fmt.Sprintf("%s must be true or false, got %q", key, v))
+		return def
+	}
+	return b
+}
+
+// mapping reads comma separated key=value pairs such as "acme=C9,globex=C3".
+// Keys are lowercased.
+func (e *envReader) mapping(key string) map[string]string {
+	out := map[string]string{}
+	for _, item := range e.list(key) {
+		k, v, ok := strings.Cut(item, "=")
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		if !ok || k == "" || v == "" {
+			e.problems = append(e.problems, fmt.Sprintf("%s entries must look like key=value, got %q", key, item))
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// listMapping reads key=value pairs like mapping whose values are lists
+// separated by "|", such as "acme=C1|C4,globex=C9". Order is kept.
+func (e *envReader) listMapping(key string) map[string][]string {
+	out := map[string][]string{}
+	for k, v := range e.mapping(key) {
+		for _, item := range strings.Split(v, "|") {
+			if item = strings.TrimSpace(item); item != "" {
+				out[k] = append(out[k], item)
+			}
+		}
+	}
+	return out
+}
+
+// jsonListMapping reads a JSON object whose values are a string or a list of
+// strings, such as {"acme": ["https://eu.example.com", "https://us.example.com"],
+// "globex": "https://eu.example.com"}, from key or from the file named by
+// fileKey. Keys are lowercased.
+func (e *envReader) jsonListMapping(key, fileKey string) map[string][]string {
+	out := map[string][]string{}
+	raw, path := strings.TrimSpace(os.Getenv(key)), strings.TrimSpace(os.Getenv(fileKey))
+	if raw != "" && path != "" {
+		e.problems = append(e.problems, fmt.Sprintf("set %s or %s, not both", key, fileKey))
+		return out
+	}
+	source := key
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			e.problems = append(e.problems, fmt.Sprintf("%s: %v", fileKey, err))
+			return out
+		}
+		raw, source = string(data), path
+	}
+	if raw == "" {
+		return out
+	}
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		e.problems = append(e.problems, fmt.Sprintf("%s must be a JSON object: %v", source, err))
+		return out
+	}
+	for k, v := range parsed {
+		var values []string
+		var single string
+		if err := json.Unmarshal(v, &single); err == nil {
+			values = []string{single}
+		} else if err := json.Unmarshal(v, &values); err != nil {
+			e.problems = append(e.problems, fmt.Sprintf("%s: %q must map to a string or a list of strings", source, k))
+			continue
+		}
+		k = strings.ToLower(strings.TrimSpace(k))
+		for _, value := range values {
+			if value = strings.TrimSpace(value); value != "" {
+				out[k] = append(out[k], value)
+			}
+		}
+	}
+	return out
+}
+
+// patterns reads semicolon separated key=regex pairs such as
+// "acme=^ACME-[0-9]{6}$;globex=^GX". Semicolons rather than commas, because
+// commas are common in regexes. Keys are lowercased.
+func (e *envReader) patterns(key string) map[string]*regexp.Regexp {
+	out := map[string]*regexp.Regexp{}
+	for _, item := range strings.Split(os.Getenv(key), ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(item, "=")
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		if !ok || k == "" || v == "" {
+			e.problems = append(e.problems, fmt.Sprintf("%s entries must look like key=regex, got %q", key, item))
+			continue
+		}
+		re, err := regexp.Compile(v)
+		if err != nil {
+			e.problems = append(e.problems, fmt.Sprintf("%s has an invalid regex for %q: %v", key, k, err))
+			continue
+		}
+		out[k] = re
+	}
+	return out
+}
+
+// list reads a comma separated value, dropping blanks.
+func (e *envReader) list(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
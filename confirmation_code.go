@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --- CONFIRMATION CODES ---
+
+// checkConfirmationCode enforces the company's CONFIRMATION_CODE_FORMATS
+// pattern on a client-supplied code. Companies without one accept any code.
+func checkConfirmationCode(cfg Config, company, code string) error {
+	format, ok := cfg.ConfirmationCodeFormats[strings.ToLower(company)]
+	if !ok || format.MatchString(code) {
+		return nil
+	}
+	return fmt.Errorf("confirmationCode %q does not match the format %s expects (%s)", code, company, format)
+}
+
+// newConfirmationCode issues a code for a booking that came without one, if
+// the company has a CONFIRMATION_CODE_PREFIXES entry. Other companies keep
+// booking without a code, as before. The rest of the code comes from the
+// ID_FORMAT generator.
+func newConfirmationCode(cfg Config, company string) (string, bool) {
+	prefix, ok := cfg.ConfirmationCodePrefixes[strings.ToLower(company)]
+	if !ok {
+		return "", false
+	}
+	return prefix + cfg.idGenerator().NewID(), true
+}
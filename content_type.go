@@ -0,0 +1,32 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireJSON answers 415 for bodies that aren't declared as JSON, so a form
+// post or a missing Content-Type gets a clear message instead of a confusing
+// bind error. application/json with parameters (charset) and +json types
+// such as application/merge-patch+json are accepted.
+func requireJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contentType := c.GetHeader("Content-Type")
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			got := contentType
+			if got == "" {
+				got = "none"
+			}
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{
+				"error": "Content-Type must be application/json (got " + got + ")",
+				"code":  "UNSUPPORTED_MEDIA_TYPE",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// handleDashboard bundles what the internal dashboard used to fetch in three
+// calls: active bookings, center availability and booking status counts.
+func handleDashboard(cfg Config, lookup *centerLookup) gin.HandlerFunc {
+	rules := cfg.capacityRules()
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		company := c.Query("company")
+		scope := bson.M{}
+		if company != "" {
+			if err := validateCompanyName(company); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_COMPANY"})
+				return
+			}
+			scope = companyVehicleFilter(company)
+		}
+
+		// --- ACTIVE BOOKINGS ---
+		activeFilter := bson.M{"status": bson.M{"$nin": inactiveStatuses}}
+		for k, v := range scope {
+			activeFilter[k] = v
+		}
+		cursor, err := bookingCollection.Find(ctx, activeFilter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		bookings := []DBBooking{}
+		if err = cursor.All(ctx, &bookings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding bookings"})
+			return
+		}
+
+		// --- STATUS COUNTS ---
+		pipeline := []bson.M{
+			{"$match": scope},
+			{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+		}
+		countCursor, err := bookingCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count bookings"})
+			return
+		}
+		defer countCursor.Close(ctx)
+
+		var groups []struct {
+			Status string `bson:"_id"`
+			Count  int    `bson:"count"`
+		}
+		if err = countCursor.All(ctx, &groups); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding booking counts"})
+			return
+		}
+		statusCounts := map[string]int{}
+		for _, g := range groups {
+			statusCounts[g.Status] = g.Count
+		}
+
+		// --- CENTERS ---
+		centers, err := lookup.activeCenters(ctx)
+		if err != nil {
+			respondCenterLookupError(c, err)
+			return
+		}
+		availability := make([]CenterAvailability, 0, len(centers))
+		for _, center := range centers {
+			availability = append(availability, rules.centerAvailability(center, time.Time{}))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"company":      company,
+			"bookings":     bookings,
+			"centers":      availability,
+			"statusCounts": statusCounts,
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- DEAD LETTERS ---
+
+// Stages a booking can fail at for good.
+const (
+	DeadLetterSelection = "selection" // center lookup or slot reservation
+	DeadLetterWrite     = "write"     // booking + log write
+	DeadLetterSync      = "sync"      // background push to 'auto_ai_db'
+)
+
+var errDeadLetterNotFound = errors.New("dead letter not found")
+
+// A replay answered with replayFailedStatus or above may be replayed again.
+const replayFailedStatus = http.StatusBadRequest
+
+// DeadLetter keeps a booking that failed after every retry, so it can be
+// reviewed and replayed instead of only showing up in stdout.
+type DeadLetter struct {
+	ID      string                 `json:"id" bson:"deadLetterId"`
+	Stage   string                 `json:"stage" bson:"stage"`
+	Reason  string                 `json:"reason" bson:"reason"`
+	Request IncomingBookingRequest `json:"request" bson:"request"`
+	Options BookingOptions         `json:"options" bson:"options"` // replayed with the request
+	// Booking is the saved booking a failed sync was pushing.
+	Booking   *DBBooking `json:"booking,omitempty" bson:"booking,omitempty"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+
+	ReplayedAt   *time.Time `json:"replayedAt,omitempty" bson:"replayedAt,omitempty"`
+	ReplayStatus int        `json:"replayStatus,omitempty" bson:"replayStatus,omitempty"` // HTTP status of the last replay; 0 while one runs
+}
+
+func generateDeadLetterID(cfg Config) string {
+	return "DL_" + cfg.idGenerator().NewID()
+}
+
+// recordDeadLetter stores a failed booking. Failing to store it is only
+// logged; the caller is already answering with the original error.
+func recordDeadLetter(ctx context.Context, cfg Config, stage string, req IncomingBookingRequest, opts BookingOptions, booking *DBBooking, reason error) {
+	dl := DeadLetter{
+		ID:        generateDeadLetterID(cfg),
+		Stage:     stage,
+		Reason:    reason.Error(),
+		Request:   req,
+		Options:   opts,
+		Booking:   booking,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := store.SaveDeadLetter(ctx, dl); err != nil {
+		fmt.Printf("❌ Could not dead-letter %s booking for %s: %v\n", stage, req.VehicleID, err)
+		return
+	}
+	fmt.Printf("⚠️ Dead-lettered %s failure for %s as %s\n", stage, req.VehicleID, dl.ID)
+}
+
+// handleGetDeadLetters lists dead letters, newest first by default.
+func handleGetDeadLetters(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := parseListParams(c, "createdAt")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if list.SortField == "" {
+			list.SortField = "createdAt"
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		deadLetters, total, err := store.FindDeadLetters(ctx, list)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dead letters"})
+			return
+		}
+		setPaginationHeaders(c, total, list.Page)
+		c.JSON(http.StatusOK, deadLetters)
+	}
+}
+
+// handleReplayDeadLetter reprocesses a dead letter. Failed syncs push the
+// saved booking again; everything else books the original request again with
+// its original options, answering as /book-service would. A replay that fails
+// again is dead-lettered again.
+func handleReplayDeadLetter(cfg Config, lookup *centerLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		dl, err := store.FindDeadLetter(ctx, c.Param("id"))
+		if err == errDeadLetterNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found", "code": "DEAD_LETTER_NOT_FOUND"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dead letter"})
+			return
+		}
+		if dl.ReplayedAt != nil && dl.ReplayStatus < replayFailedStatus {
+			c.JSON(http.StatusConflict, gin.H{"error": "Dead letter was already replayed", "code": "ALREADY_REPLAYED"})
+			return
+		}
+		// The check above is only a fast path. Two replays can both pass it,
+		// and only the one that claims the dead letter books it again.
+		dl, err = store.ClaimDeadLetterReplay(ctx, dl.ID, time.Now().UTC())
+		if err == errDeadLetterNotFound {
+			c.JSON(http.StatusConflict, gin.H{"error": "Dead letter was already replayed", "code": "ALREADY_REPLAYED"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim dead letter"})
+			return
+		}
+
+		if dl.Stage == DeadLetterSync && dl.Booking != nil {
+			alreadySynced, err := pushBookingToCenter(ctx, *dl.Booking)
+			if err != nil {
+				fmt.Printf("❌ Replay of %s failed: %v\n", dl.ID, err)
+				c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to update service center: " + err.Error(), "code": "SYNC_FAILED"})
+			} else {
+				c.JSON(http.StatusOK, gin.H{"id": dl.ID, "synced": true, "alreadySynced": alreadySynced})
+			}
+		} else {
+			bookService(c, cfg, lookup, dl.Request, dl.Options, time.Now().Add(cfg.BookingDeadline))
+		}
+
+		now := time.Now().UTC()
+		dl.ReplayedAt = &now
+		dl.ReplayStatus = c.Writer.Status()
+		if err := store.SaveDeadLetter(ctx, dl); err != nil {
+			fmt.Printf("⚠️ Could not record replay of %s: %v\n", dl.ID, err)
+		}
+	}
+}
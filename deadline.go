@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- BOOKING DEADLINE ---
+
+// Extra time, past BOOKING_DEADLINE, for the one write that records what
+// happened to a booking that ran out of time (its waitlist entry or dead
+// letter). It bounds the worst-case wait at the deadline plus this.
+const deadlineWriteGrace = 2 * time.Second
+
+// pastDeadline reports whether err came from the booking deadline running out.
+func pastDeadline(ctx context.Context, err error) bool {
+	return err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// respondBookingDeadline answers a booking that ran out of time before a
+// center was reserved: with WAITLIST_ON_DEADLINE it is saved as PENDING
+// without a center (202), otherwise it is dead-lettered and the client gets
+// a prompt 504 instead of waiting on a slow upstream.
+func respondBookingDeadline(ctx context.Context, cfg Config, c *gin.Context, company string, req, original IncomingBookingRequest, opts BookingOptions, before *DBBooking, logID string, cause error) {
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deadlineWriteGrace)
+	defer cancel()
+
+	if !cfg.WaitlistOnDeadline {
+		recordDeadLetter(writeCtx, cfg, DeadLetterSelection, original, opts, nil, cause)
+		respondDeadlineExceeded(cfg, c)
+		return
+	}
+
+	now := time.Now().UTC()
+	booking := DBBooking{
+		VehicleID:        req.VehicleID,
+		Company:          company,
+		ConfirmationCode: req.ConfirmationCode,
+		Status:           StatusPending,
+		ScheduledService: ScheduledService{DateTime: req.ScheduledService.DateTime},
+		Priority:         req.Priority,
+		Action:           req.Action,
+		Metadata:         req.Metadata,
+		UserID:           "USR_" + req.VehicleID,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if booking.ConfirmationCode == "" && before == nil {
+		if code, ok := newConfirmationCode(cfg, company); ok {
+			booking.ConfirmationCode = code
+		}
+	}
+	entry := bookingLogEntry(cfg, booking, "WAITLISTED_AFTER_DEADLINE")
+	entry.LogID = logID
+
+	previous := DBBooking{}
+	if before != nil {
+		previous = *before
+	}
+	changes := bookingChanges(previous, booking, withUserID(booking).UserID, now)
+	logPersisted, err := store.SaveBookingWithLog(writeCtx, booking, before != nil, changes, entry)
+	if err != nil {
+		fmt.Println("❌ Could not waitlist booking:", err)
+		recordDeadLetter(writeCtx, cfg, DeadLetterWrite, original, opts, nil, err)
+		respondDeadlineExceeded(cfg, c)
+		return
+	}
+
+	fmt.Printf("⚠️ Booking for %s ran past %s, waitlisted as PENDING\n", req.VehicleID, cfg.BookingDeadline)
+	response := gin.H{
+		"bookingStatus":  booking.Status,
+		"generatedLogId": logID,
+		"message":        "Booking waitlisted; a service center will be assigned later",
+		"waitlisted":     true,
+		"logPersisted":   logPersisted,
+	}
+	if booking.ConfirmationCode != "" {
+		response["confirmationCode"] = booking.ConfirmationCode
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
+// waitlistBefore is the booking a waitlist entry replaces, if any.
+func waitlistBefore(isUpdate bool, existing DBBooking) *DBBooking {
+	if isUpdate {
+		return &existing
+	}
+	return nil
+}
+
+func respondDeadlineExceeded(cfg Config, c *gin.Context) {
+	c.JSON(http.StatusGatewayTimeout, gin.H{
+		"error": fmt.Sprintf("Booking could not be completed within %s, please retry", cfg.BookingDeadline),
+		"code":  "BOOKING_DEADLINE_EXCEEDED",
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// handleDebugCenters shows support exactly what the center source holds for
+// ?company= (optional): every center, inactive ones and full booking arrays
+// included. With 'auto_ai_db' the documents are returned as stored, fields
+// this service doesn't know about included. The lookup bypasses the circuit
+// breaker and lookup stats, so debugging doesn't trip or skew them.
+func handleDebugCenters(cfg Config, lookup *centerLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		company := c.Query("company")
+		if company != "" {
+			if err := validateCompanyName(company); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		var centers interface{}
+		count := 0
+		source := "centersFile"
+		switch {
+		case serviceCenterCollection != nil:
+			source = "auto_ai_db"
+			cursor, err := serviceCenterCollection.Find(ctx, bson.M{})
+			if err != nil {
+				fmt.Println("❌ Debug center query failed:", err)
+				c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to query service centers: " + err.Error()})
+				return
+			}
+			docs := []bson.M{}
+			if err := cursor.All(ctx, &docs); err != nil {
+				c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to decode service centers: " + err.Error()})
+				return
+			}
+			centers, count = docs, len(docs)
+		case centerProvider != nil:
+			list, err := centerProvider.CentersForCompany(ctx, company)
+			if err != nil {
+				c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to query service centers: " + err.Error()})
+				return
+			}
+			centers, count = list, len(list)
+		default:
+			respondCenterLookupError(c, errCenterSourceUnavailable)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"company":      company,
+			"source":       source,
+			"count":        count,
+			"circuitState": lookup.breaker.State().String(),
+			"centers":      centers,
+		})
+	}
+}
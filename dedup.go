@@ -0,0 +1,26 @@
+package main
+
+import "time"
+
+// isRecentDuplicate reports whether req repeats the vehicle's active booking:
+// made or changed in the last cfg.DedupWindow for (roughly) the same time, so
+// a double-submitted form returns the first booking instead of touching it
+// again. A request carrying a different confirmation code is a separate
+// booking on purpose and is never treated as a duplicate.
+func isRecentDuplicate(cfg Config, existing DBBooking, req IncomingBookingRequest, scheduledAt time.Time) bool {
+	if cfg.DedupWindow <= 0 || time.Since(existing.UpdatedAt) > cfg.DedupWindow {
+		return false
+	}
+	if req.ConfirmationCode != "" && req.ConfirmationCode != existing.ConfirmationCode {
+		return false
+	}
+	if scheduledAt.IsZero() {
+		return existing.ScheduledService.DateTime == req.ScheduledService.DateTime
+	}
+	existingAt, err := parseFlexibleTime(existing.ScheduledService.DateTime)
+	if err != nil {
+		return false
+	}
+	diff := existingAt.Sub(scheduledAt)
+	return diff >= -cfg.DedupTimeTolerance && diff <= cfg.DedupTimeTolerance
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- RESPONSE ENVELOPE ---
+
+// envelope is the uniform shape some clients want every JSON response in.
+// Exactly one of Data and Error is set; the other encodes as null.
+type envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Error json.RawMessage `json:"error"`
+	Meta  envelopeMeta    `json:"meta"`
+}
+
+type envelopeMeta struct {
+	Status     int  `json:"status"`
+	TotalCount *int `json:"totalCount,omitempty"` // mirrors X-Total-Count on list endpoints
+}
+
+// responseEnvelope wraps JSON responses as {"data":..., "error":..., "meta":...}
+// when RESPONSE_ENVELOPE is set or the request asks with ?envelope=true
+// (?envelope=false opts back out). Bare bodies stay the default. Non-JSON
+// responses (XML, NDJSON exports, 304s) pass through untouched.
+func responseEnvelope(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wanted := cfg.ResponseEnvelope
+		if v, err := strconv.ParseBool(c.Query("envelope")); err == nil {
+			wanted = v
+		}
+		if !wanted {
+			c.Next()
+			return
+		}
+
+		w := &envelopeWriter{ResponseWriter: c.Writer, status: c.Writer.Status()}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.buffering {
+			if !w.decided {
+				w.ResponseWriter.WriteHeader(w.status)
+			}
+			return
+		}
+		body, err := json.Marshal(wrapBody(w.status, w.body.Bytes(), w.Header().Get("X-Total-Count")))
+		if err != nil {
+			// The handler's own body is still valid JSON; send it bare.
+			body = w.body.Bytes()
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// wrapBody puts a handler's JSON body into the envelope. Error bodies keep
+// their fields, with "error" renamed to "message" so it doesn't read
+// error.error.
+func wrapBody(status int, body []byte, totalCount string) envelope {
+	wrapped := envelope{Meta: envelopeMeta{Status: status}}
+	if n, err := strconv.Atoi(totalCount); err == nil {
+		wrapped.Meta.TotalCount = &n
+	}
+	if status < 400 {
+		wrapped.Data = body
+		return wrapped
+	}
+
+	wrapped.Error = body
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) == nil {
+		if message, ok := fields["error"]; ok {
+			delete(fields, "error")
+			fields["message"] = message
+			if renamed, err := json.Marshal(fields); err == nil {
+				wrapped.Error = renamed
+			}
+		}
+	}
+	return wrapped
+}
+
+// envelopeWriter holds back JSON bodies so they can be wrapped once the
+// handler is done. Whether to hold back is decided on the first write, from
+// the Content-Type the handler set; anything else is streamed straight through.
+type envelopeWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	status    int
+	decided   bool
+	buffering bool
+}
+
+func (w *envelopeWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *envelopeWriter) WriteHeader(code int) {
+	if w.decided && !w.buffering {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *envelopeWriter) WriteHeaderNow() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *envelopeWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *envelopeWriter) Status() int {
+	if w.decided && !w.buffering {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *envelopeWriter) Size() int {
+	if w.buffering {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *envelopeWriter) Written() bool {
+	return w.buffering || w.ResponseWriter.Written()
+}
+
+func (w *envelopeWriter) Flush() {
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondJSONWithETag sends payload with a strong ETag derived from the
+// encoded body (plus any headers that vary with it, such as X-Total-Count),
+// and answers 304 when the client already holds that version.
+func respondJSONWithETag(c *gin.Context, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+
+	hash := sha256.New()
+	hash.Write(body)
+	hash.Write([]byte(c.Writer.Header().Get("X-Total-Count")))
+	etag := `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
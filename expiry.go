@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// --- PENDING BOOKING EXPIRY ---
+
+// runPendingExpiry periodically expires PENDING bookings older than
+// PENDING_TTL so abandoned bookings stop holding center capacity.
+func runPendingExpiry(cfg Config) {
+	ticker := time.NewTicker(cfg.PendingSweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
+		expired, err := expirePendingBookings(ctx, cfg, time.Now().UTC().Add(-cfg.PendingTTL))
+		cancel()
+		if err != nil {
+			fmt.Println("⚠️ Pending booking sweep failed:", err)
+		}
+		if expired > 0 {
+			fmt.Printf("🔄 Expired %d pending booking(s)\n", expired)
+		}
+	}
+}
+
+// expirePendingBookings moves every PENDING booking created before cutoff to
+// EXPIRED with a BOOKING_EXPIRED log and frees its center slot. Bookings
+// confirmed while the sweep runs are left alone.
+func expirePendingBookings(ctx context.Context, cfg Config, cutoff time.Time) (int, error) {
+	bookings, _, err := store.FindBookings(ctx, BookingQuery{Status: StatusPending, CreatedBefore: cutoff})
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, booking := range bookings {
+		entry := bookingLogEntry(cfg, booking, "BOOKING_EXPIRED")
+		entry.Data.Status = StatusExpired
+		if err := store.SetBookingStatus(ctx, booking, StatusExpired, ActorSystem, entry); err != nil {
+			if !errors.Is(err, errBookingNotFound) {
+				fmt.Printf("❌ Could not expire %s: %v\n", booking.ConfirmationCode, err)
+			}
+			continue
+		}
+		expired++
+
+		if err := releaseCenterSlot(ctx, booking); err != nil {
+			fmt.Printf("⚠️ Could not release slot at %s for %s: %v\n", booking.ScheduledService.ServiceCenterID, booking.ConfirmationCode, err)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// selectionStrategy names how auto-assignment ranks centers (rankCenters).
+const selectionStrategy = "least booked slots"
+
+// CenterEvaluation is how one center fared in auto-assignment, for
+// /book-service?explain=true.
+type CenterEvaluation struct {
+	CenterAvailability
+	Active     bool   `json:"active"`
+	ExcludedBy string `json:"excludedBy,omitempty"`
+	Rank       int    `json:"rank,omitempty"` // 1 is the preferred candidate
+}
+
+// explainSelection lays out every center that was considered, why any were
+// excluded and where the rest ranked. The score is the chosen center's booked
+// slots at the requested time; lower wins.
+func (rules capacityRules) explainSelection(centers []ServiceCenterDBModel, ranked []*ServiceCenterDBModel, at time.Time, chosenID string) gin.H {
+	ranks := map[string]int{}
+	for i, center := range ranked {
+		ranks[center.ID] = i + 1
+	}
+
+	considered := make([]CenterEvaluation, 0, len(centers))
+	score := -1
+	for _, center := range centers {
+		considered = append(considered, CenterEvaluation{
+			CenterAvailability: rules.centerAvailability(center, at),
+			Active:             center.IsActive,
+			ExcludedBy:         rules.centerExclusion(center, at),
+			Rank:               ranks[center.ID],
+		})
+		if center.ID == chosenID {
+			score = rules.bookedSlotsAt(center, at)
+		}
+	}
+
+	return gin.H{
+		"strategy":   selectionStrategy,
+		"considered": considered,
+		"chosen":     chosenID,
+		"score":      score,
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"unicode/utf8"
+)
+
+// --- FIELD LENGTH LIMITS ---
+
+const defaultMaxFieldLength = 256
+
+// namedField is an incoming string value with the JSON path reported when it
+// is too long.
+type namedField struct {
+	name  string
+	value string
+}
+
+// checkFieldLengths rejects the first value longer than max characters, so a
+// buggy client can't store megabytes in a single field.
+func checkFieldLengths(max int, fields []namedField) error {
+	for _, f := range fields {
+		if n := utf8.RuneCountInString(f.value); n > max {
+			return fmt.Errorf("%s is %d characters long; the limit is %d", f.name, n, max)
+		}
+	}
+	return nil
+}
+
+func (r IncomingBookingRequest) stringFields() []namedField {
+	fields := []namedField{
+		{"vehicleId", r.VehicleID},
+		{"confirmationCode", r.ConfirmationCode},
+		{"status", r.Status},
+		{"priority", r.Priority},
+		{"action", r.Action},
+		{"scheduledService.serviceCenterId", r.ScheduledService.ServiceCenterID},
+		{"scheduledService.dateTime", r.ScheduledService.DateTime},
+	}
+	for _, key := range slices.Sorted(maps.Keys(r.Metadata)) {
+		fields = append(fields, namedField{"metadata key " + key, key}, namedField{"metadata." + key, r.Metadata[key]})
+	}
+	return fields
+}
+
+func (e LogEntry) stringFields() []namedField {
+	return []namedField{
+		{"logId", e.LogID},
+		{"userId", e.UserID},
+		{"vehicleId", e.VehicleID},
+		{"timestamp", e.Timestamp},
+		{"logType", e.LogType},
+		{"data.confirmationCode", e.Data.ConfirmationCode},
+		{"data.status", e.Data.Status},
+		{"data.serviceCenterId", e.Data.ServiceCenterID},
+		{"data.scheduledAt", e.Data.ScheduledAt},
+		{"data.action", e.Data.Action},
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// --- HEALTH HISTORY ---
+
+// HealthSample is one /system-status evaluation.
+type HealthSample struct {
+	Timestamp  time.Time `json:"timestamp"`
+	DatabaseOK bool      `json:"databaseOk"`
+	UpstreamOK bool      `json:"upstreamOk"`
+	Status     string    `json:"status"`
+}
+
+// healthRing keeps the last evaluations in a fixed-size ring, so flapping
+// shows up without a metrics stack.
+type healthRing struct {
+	mu      sync.Mutex
+	samples []HealthSample
+	next    int
+	full    bool
+}
+
+const defaultHealthHistorySize = 50
+
+func newHealthRing(size int) *healthRing {
+	return &healthRing{samples: make([]HealthSample, size)}
+}
+
+func (r *healthRing) record(sample HealthSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the recorded samples, oldest first.
+func (r *healthRing) list() []HealthSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]HealthSample{}, r.samples[:r.next]...)
+	}
+	return append(append([]HealthSample{}, r.samples[r.next:]...), r.samples[:r.next]...)
+}
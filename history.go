@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// --- BOOKING HISTORY ---
+
+// ChangeEvent is one field change recorded on the booking itself, so a single
+// booking can be audited without joining to the Logs collection.
+type ChangeEvent struct {
+	Timestamp time.Time `json:"timestamp" bson:"timestamp" xml:"timestamp"`
+	Field     string    `json:"field" bson:"field" xml:"field"`
+	Old       string    `json:"old" bson:"old" xml:"old"`
+	New       string    `json:"new" bson:"new" xml:"new"`
+	Actor     string    `json:"actor" bson:"actor" xml:"actor"` // the booking's userId, ActorSystem or ActorOps
+}
+
+// Actors for changes no client asked for.
+const (
+	ActorSystem = "system" // background jobs such as the pending expiry sweep
+	ActorOps    = "ops"    // ops endpoints such as bulk cancel
+)
+
+// defaultBookingHistoryLimit is how many events a booking keeps, newest
+// last, unless BOOKING_HISTORY_LIMIT says otherwise.
+const defaultBookingHistoryLimit = 20
+
+// bookingChanges lists the tracked fields that differ between before and
+// after. A new booking is compared against the zero DBBooking, so its
+// history starts with the values it was created with.
+func bookingChanges(before, after DBBooking, actor string, at time.Time) []ChangeEvent {
+	fields := []struct{ name, old, new string }{
+		{"status", before.Status, after.Status},
+		{"confirmationCode", before.ConfirmationCode, after.ConfirmationCode},
+		{"scheduledService.serviceCenterId", before.ScheduledService.ServiceCenterID, after.ScheduledService.ServiceCenterID},
+		{"scheduledService.dateTime", before.ScheduledService.DateTime, after.ScheduledService.DateTime},
+		{"scheduledService.isScheduled", strconv.FormatBool(before.ScheduledService.IsScheduled), strconv.FormatBool(after.ScheduledService.IsScheduled)},
+		{"priority", before.Priority, after.Priority},
+		{"action", before.Action, after.Action},
+	}
+	changes := []ChangeEvent{}
+	for _, f := range fields {
+		if f.old != f.new {
+			changes = append(changes, ChangeEvent{Timestamp: at, Field: f.name, Old: f.old, New: f.new, Actor: actor})
+		}
+	}
+	return changes
+}
+
+// statusChange records a status-only transition.
+func statusChange(booking DBBooking, status, actor string) ChangeEvent {
+	return ChangeEvent{Timestamp: time.Now().UTC(), Field: "status", Old: booking.Status, New: status, Actor: actor}
+}
+
+// appendHistory adds changes and drops the oldest events beyond limit.
+func appendHistory(history, changes []ChangeEvent, limit int) []ChangeEvent {
+	history = append(append([]ChangeEvent{}, history...), changes...)
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	return history
+}
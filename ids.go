@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	mathrand "math/rand"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// --- ID GENERATION ---
+
+// ID formats, set from ID_FORMAT.
+const (
+	IDFormatULID   = "ulid"   // time-sortable, collision-safe across replicas
+	IDFormatUUID   = "uuid"   // random v4
+	IDFormatLegacy = "legacy" // date plus 4 random digits; collides across replicas
+)
+
+// IDGenerator issues the unique part of generated IDs. Callers add their own
+// prefix: "LOG_", "DL_" or a company's confirmation code prefix.
+type IDGenerator interface {
+	NewID() string
+}
+
+// idGenerator is the generator for ID_FORMAT.
+func (cfg Config) idGenerator() IDGenerator {
+	return newIDGenerator(cfg.IDFormat)
+}
+
+func newIDGenerator(format string) IDGenerator {
+	switch format {
+	case IDFormatUUID:
+		return uuidGenerator{}
+	case IDFormatLegacy:
+		return legacyIDGenerator{}
+	default:
+		return ulidGenerator{}
+	}
+}
+
+// crockfordBase32 is the ULID alphabet: no I, L, O or U.
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator issues ULIDs: a 48-bit millisecond timestamp and 80 random
+// bits, 26 characters that sort by creation time.
+type ulidGenerator struct{}
+
+func (ulidGenerator) NewID() string {
+	var id [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	rand.Read(id[6:])
+
+	// 128 bits as 26 base32 digits, the first of which carries 3 bits.
+	var out [26]byte
+	hi := uint64(id[0])<<56 | uint64(id[1])<<48 | uint64(id[2])<<40 | uint64(id[3])<<32 |
+		uint64(id[4])<<24 | uint64(id[5])<<16 | uint64(id[6])<<8 | uint64(id[7])
+	lo := uint64(id[8])<<56 | uint64(id[9])<<48 | uint64(id[10])<<40 | uint64(id[11])<<32 |
+		uint64(id[12])<<24 | uint64(id[13])<<16 | uint64(id[14])<<8 | uint64(id[15])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+type uuidGenerator struct{}
+
+func (uuidGenerator) NewID() string {
+	return uuid.NewString()
+}
+
+// legacyIDGenerator keeps the original date-plus-digits format, e.g.
+// 20240501_0042.
+type legacyIDGenerator struct{}
+
+func (legacyIDGenerator) NewID() string {
+	return fmt.Sprintf("%s_%04d", time.Now().Format("20060102"), mathrand.Intn(10000))
+}
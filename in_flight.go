@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMaxInFlight = 256
+	// Seconds a shed client is asked to wait; in-flight requests finish
+	// within REQUEST_TIMEOUT, usually far sooner.
+	inFlightRetryAfter = "1"
+)
+
+// limitInFlight sheds load once maxInFlight requests are being served: extra
+// requests get 503 with Retry-After straight away instead of queueing for a
+// Mongo connection or an upstream call. /system-status is exempt so health
+// checks keep answering during a spike.
+func limitInFlight(maxInFlight int) gin.HandlerFunc {
+	slots := make(chan struct{}, maxInFlight)
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.FullPath(), "/system-status") {
+			c.Next()
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			c.Next()
+		default:
+			c.Header("Retry-After", inFlightRetryAfter)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Server is busy, please retry shortly", "code": "TOO_MANY_IN_FLIGHT"})
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redacted stands in for secrets in /internal/config.
+const redacted = "[redacted]"
+
+// handleInternalConfig reports the configuration this instance is actually
+// running with, so ops can check it without reading the host's environment.
+// Secrets only show whether they are set.
+func handleInternalConfig(cfg Config) gin.HandlerFunc {
+	secret := func(value string) string {
+		if value == "" {
+			return ""
+		}
+		return redacted
+	}
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"store":          cfg.Store,
+			"centersFile":    cfg.CentersFile,
+			"mongoUri":       secret(cfg.MongoURI),
+			"dbName":         cfg.DBName,
+			"adminApiUrl":    cfg.AdminAPIURL,
+			"centerSource":   cfg.CenterSource,
+			"adminApiRoutes": cfg.AdminAPIRoutes,
+			"adminApiWakeUp": cfg.AdminAPIWakeUp,
+			"adminToken":     secret(cfg.AdminToken),
+			"otlpEndpoint":   cfg.OTLPEndpoint,
+			"idFormat":       cfg.IDFormat,
+			"selection": gin.H{
+				"strategy":          selectionStrategy,
+				"maxCenterAttempts": cfg.MaxCenterAttempts,
+				"missingCapacity":   cfg.MissingCapacity,
+				"slotDuration":      cfg.SlotDuration.String(),
+				"fallbackCenters":   cfg.FallbackCenters,
+				"preferredCenters":  cfg.PreferredCenters,
+			},
+			"timeouts": gin.H{
+				"request":      cfg.RequestTimeout.String(),
+				"centerSync":   cfg.CenterSyncTimeout.String(),
+				"mongoConnect": cfg.MongoConnectTimeout.String(),
+				"coldLookup":   cfg.ColdLookupThreshold.String(),
+			},
+			"centerLookups": gin.H{
+				"maxConcurrency":          cfg.MaxExternalConcurrency,
+				"circuitFailureThreshold": cfg.CircuitFailureThreshold,
+				"circuitCooldown":         cfg.CircuitCooldown.String(),
+			},
+			"features": gin.H{
+				"disableRemoteSync":             cfg.DisableRemoteSync,
+				"requireSyncCompanies":          cfg.RequireSyncCompanies,
+				"warmUpCenterLookup":            cfg.WarmUpCenterLookup,
+				"startupSelfTest":               cfg.StartupSelfTest,
+				"singleActiveBookingPerVehicle": cfg.SingleActiveBookingPerVehicle,
+				"allowedCompanies":              cfg.AllowedCompanies,
+			},
+			"bookings": gin.H{
+				"dedupWindow":          cfg.DedupWindow.String(),
+				"dedupTimeTolerance":   cfg.DedupTimeTolerance.String(),
+				"pendingTtl":           cfg.PendingTTL.String(),
+				"pendingSweepInterval": cfg.PendingSweepInterval.String(),
+			},
+			"writes": gin.H{
+				"writeConcern":       cfg.WriteConcern,
+				"mongoWriteRetries":  cfg.MongoWriteRetries,
+				"logDuplicatePolicy": cfg.LogDuplicatePolicy,
+				"logContentDedup":    cfg.LogContentDedupWindow.String(),
+			},
+		})
+	}
+}
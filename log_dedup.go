@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- LOG CONTENT DEDUP ---
+
+// logHashesCollection remembers recent log contents for
+// LOG_CONTENT_DEDUP_WINDOW; a TTL index drops them afterwards.
+var logHashesCollection *mongo.Collection
+
+// logContentHash identifies an event by what it says rather than its logId,
+// so a producer emitting the same event twice under new IDs is caught.
+func logContentHash(entry LogEntry) string {
+	h := sha256.New()
+	for _, field := range []string{entry.VehicleID, entry.LogType, entry.Timestamp, entry.Data.Action} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// claimLogContent records that logID carries this content until window has
+// passed. If another live entry already carries it, claimed is false and
+// existingLogID names that entry.
+func claimLogContent(ctx context.Context, hash, logID string, window time.Duration) (existingLogID string, claimed bool, err error) {
+	now := time.Now().UTC()
+	doc := bson.M{"hash": hash, "logId": logID, "expireAt": now.Add(window)}
+
+	// Reuse an expired record the TTL monitor hasn't removed yet, or create one.
+	res, err := logHashesCollection.UpdateOne(ctx,
+		bson.M{"hash": hash, "expireAt": bson.M{"$lte": now}},
+		bson.M{"$set": doc})
+	if err != nil {
+		return "", false, err
+	}
+	if res.MatchedCount > 0 {
+		return "", true, nil
+	}
+	_, err = logHashesCollection.InsertOne(ctx, doc)
+	if err == nil {
+		return "", true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return "", false, err
+	}
+
+	var existing struct {
+		LogID string `bson:"logId"`
+	}
+	if err := logHashesCollection.FindOne(ctx, bson.M{"hash": hash}).Decode(&existing); err != nil {
+		return "", false, err
+	}
+	return existing.LogID, false, nil
+}
+
+// releaseLogContent forgets a claim whose log entry was never written, so a
+// retry isn't taken for a duplicate.
+func releaseLogContent(ctx context.Context, hash, logID string) {
+	if _, err := logHashesCollection.DeleteOne(ctx, bson.M{"hash": hash, "logId": logID}); err != nil {
+		fmt.Println("⚠️ Could not release log content hash:", err)
+	}
+}
+
+// ensureLogHashIndexes makes hashes unique and lets MongoDB expire them.
+func ensureLogHashIndexes(ctx context.Context) {
+	_, err := logHashesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expireAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		fmt.Println("⚠️ Could not create LogHashes indexes; content dedup may let duplicates through:", err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// What POST /logs does with a logId that is already stored, set from
+// LOG_DUPLICATE_POLICY.
+const (
+	LogDuplicateReject    = "reject"    // 409; the stored entry wins
+	LogDuplicateIgnore    = "ignore"    // 200 without writing; replays are harmless
+	LogDuplicateOverwrite = "overwrite" // the new entry replaces the stored one
+)
+
+// handleCreateLog stores a log entry sent by another service. Entries without
+// a logId get one generated, and a missing timestamp defaults to now.
+func handleCreateLog(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var entry LogEntry
+		if err := c.ShouldBindJSON(&entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: " + err.Error()})
+			return
+		}
+		if strings.TrimSpace(entry.VehicleID) == "" || strings.TrimSpace(entry.LogType) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "vehicleId and logType are required"})
+			return
+		}
+		if err := checkFieldLengths(cfg.MaxFieldLength, entry.stringFields()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "FIELD_TOO_LONG"})
+			return
+		}
+		if entry.LogID == "" {
+			entry.LogID = generateLogID(cfg)
+		}
+		if entry.Timestamp == "" {
+			entry.Timestamp = formatTimestamp(time.Now())
+		} else {
+			t, err := parseFlexibleTime(entry.Timestamp)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timestamp: " + err.Error()})
+				return
+			}
+			entry.Timestamp = formatTimestamp(t)
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		// Same event under a different logId: keep the first. A resend with
+		// the same logId is left to LOG_DUPLICATE_POLICY.
+		contentHash := ""
+		if cfg.LogContentDedupWindow > 0 {
+			hash := logContentHash(entry)
+			existingLogID, claimed, err := claimLogContent(ctx, hash, entry.LogID, cfg.LogContentDedupWindow)
+			if err != nil {
+				fmt.Println("❌ Log content dedup failed:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save log"})
+				return
+			}
+			if !claimed && existingLogID != entry.LogID {
+				c.JSON(http.StatusOK, gin.H{"logId": existingLogID, "message": "Duplicate event ignored: an identical event was logged as " + existingLogID + " within LOG_CONTENT_DEDUP_WINDOW"})
+				return
+			}
+			if claimed {
+				contentHash = hash
+			}
+		}
+		// release undoes the content claim when this entry doesn't get written.
+		release := func() {
+			if contentHash != "" {
+				releaseLogContent(ctx, contentHash, entry.LogID)
+			}
+		}
+		failed := func() {
+			release()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save log"})
+		}
+
+		filter := bson.M{"logId": entry.LogID}
+		if cfg.LogDuplicatePolicy == LogDuplicateOverwrite {
+			var replaced bool
+			err := withWriteRetry(ctx, cfg.MongoWriteRetries, "overwrite log", func() error {
+				r, err := logsCollection.ReplaceOne(ctx, filter, entry, options.Replace().SetUpsert(true))
+				if err == nil {
+					replaced = r.UpsertedCount == 0
+				}
+				return err
+			})
+			if err != nil {
+				fmt.Println("❌ Log write failed:", err)
+				failed()
+				return
+			}
+			if replaced {
+				c.JSON(http.StatusOK, gin.H{"logId": entry.LogID, "message": "Log replaced: an entry with this logId existed and was overwritten, so the earlier version is lost"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"logId": entry.LogID, "message": "Log saved"})
+			return
+		}
+
+		// Insert only if the logId is new; the stored entry is never touched.
+		var inserted bool
+		err := withWriteRetry(ctx, cfg.MongoWriteRetries, "insert log", func() error {
+			r, err := logsCollection.UpdateOne(ctx, filter, bson.M{"$setOnInsert": entry}, options.Update().SetUpsert(true))
+			if err == nil {
+				inserted = r.UpsertedCount > 0
+			}
+			return err
+		})
+		if err != nil {
+			fmt.Println("❌ Log write failed:", err)
+			failed()
+			return
+		}
+		if !inserted {
+			release()
+		}
+		switch {
+		case inserted:
+			c.JSON(http.StatusCreated, gin.H{"logId": entry.LogID, "message": "Log saved"})
+		case cfg.LogDuplicatePolicy == LogDuplicateIgnore:
+			c.JSON(http.StatusOK, gin.H{"logId": entry.LogID, "message": "Duplicate logId ignored: the stored entry was kept and this one was not written, so any differences in it are dropped"})
+		default:
+			c.JSON(http.StatusConflict, gin.H{"error": "A log with this logId already exists; the stored entry was kept. Resend with a new logId if this is a different event", "code": "DUPLICATE_LOG_ID", "logId": entry.LogID})
+		}
+	}
+}
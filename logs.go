@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// generateLogID returns an ID like LOG_01HXYZ... (see ID_FORMAT).
+func generateLogID(cfg Config) string {
+	return "LOG_" + cfg.idGenerator().NewID()
+}
+
+// bookingLogEntry builds the audit entry for an action taken on an existing booking.
+func bookingLogEntry(cfg Config, booking DBBooking, action string) LogEntry {
+	return LogEntry{
+		LogID:     generateLogID(cfg),
+		UserID:    booking.UserID,
+		VehicleID: booking.VehicleID,
+		Timestamp: formatTimestamp(time.Now()),
+		LogType:   "BOOKING",
+		Data: LogData{
+			ConfirmationCode: booking.ConfirmationCode,
+			Status:           booking.Status,
+			ServiceCenterID:  booking.ScheduledService.ServiceCenterID,
+			ScheduledAt:      booking.ScheduledService.DateTime,
+			IsScheduled:      booking.ScheduledService.IsScheduled,
+			Action:           action,
+		},
+	}
+}
+
+// logQueryFromRequest reads ?action= (exact) or ?actionPrefix= (e.g.
+// ASSIGNED_CENTER_) into a LogQuery.
+func logQueryFromRequest(c *gin.Context, list ListParams) (LogQuery, error) {
+	q := LogQuery{Action: c.Query("action"), ActionPrefix: c.Query("actionPrefix"), List: list}
+	if q.Action != "" && q.ActionPrefix != "" {
+		return q, errors.New("use either action or actionPrefix, not both")
+	}
+	return q, nil
+}
+
+const ndjsonContentType = "application/x-ndjson"
+
+// wantsNDJSON reports whether the client asked for newline-delimited JSON,
+// via ?format=ndjson or the Accept header.
+func wantsNDJSON(c *gin.Context) bool {
+	return c.Query("format") == "ndjson" || strings.Contains(c.GetHeader("Accept"), ndjsonContentType)
+}
+
+// streamLogsNDJSON writes each log entry as its own JSON line as it is read
+// from the store, so large exports never sit in memory as one array. Once the
+// first line is out the status can't change, so a failure midway just ends
+// the stream early.
+func streamLogsNDJSON(ctx context.Context, c *gin.Context, q LogQuery) {
+	c.Header("Content-Type", ndjsonContentType)
+	c.Status(http.StatusOK)
+
+	err := encodeLogsNDJSON(ctx, c.Writer, q, c.Writer.Flush)
+	if err != nil && err != errClientGone {
+		fmt.Println("❌ NDJSON log export failed:", err)
+	}
+}
+
+// encodeLogsNDJSON writes the matching entries to w one JSON line at a time,
+// calling flush after each. A write error means the client is gone.
+func encodeLogsNDJSON(ctx context.Context, w io.Writer, q LogQuery, flush func()) error {
+	enc := json.NewEncoder(w)
+	return store.EachLog(ctx, q, func(entry LogEntry) error {
+		if err := enc.Encode(entry); err != nil {
+			return errClientGone
+		}
+		flush()
+		return nil
+	})
+}
+
+var errClientGone = errors.New("client went away")
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- CENTER LOOKUP LATENCY ---
+
+const lookupSampleSize = 20
+
+// lookupStats keeps the most recent center lookup durations so /system-status
+// can tell a slow first call (cold cluster, fresh connection pool) apart from
+// a steadily slow one.
+type lookupStats struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	total   int
+}
+
+var centerLookupStats = &lookupStats{}
+
+func (s *lookupStats) record(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = append(s.samples, d)
+	if len(s.samples) > lookupSampleSize {
+		s.samples = s.samples[1:]
+	}
+	s.total++
+}
+
+// snapshot summarizes recent lookups. The source is reported cold while the
+// latest lookup is slower than threshold; once a fast lookup follows a slow
+// first call, it has warmed up.
+func (s *lookupStats) snapshot(threshold time.Duration) gin.H {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := gin.H{"lookups": s.total, "cold": false}
+	if len(s.samples) == 0 {
+		return summary
+	}
+
+	var sum, max time.Duration
+	for _, d := range s.samples {
+		sum += d
+		if d > max {
+			max = d
+		}
+	}
+	last := s.samples[len(s.samples)-1]
+	summary["lastMs"] = last.Milliseconds()
+	summary["avgMs"] = (sum / time.Duration(len(s.samples))).Milliseconds()
+	summary["maxMs"] = max.Milliseconds()
+	summary["cold"] = last > threshold
+	return summary
+}
@@ -2,19 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // --- 1. CONFIGURATION ---
@@ -23,30 +24,50 @@ const ExternalAPIBase = "https://admin-ey-1.onrender.com"
 
 // --- 2. DATA STRUCTURES ---
 
+// The bson tags are for dead letters, which keep the original request.
 type IncomingBookingRequest struct {
-	VehicleID        string `json:"vehicleId"`
-	ConfirmationCode string `json:"confirmationCode"`
-	Status           string `json:"status"`
+	VehicleID        string `json:"vehicleId" bson:"vehicleId" binding:"required"`
+	ConfirmationCode string `json:"confirmationCode" bson:"confirmationCode"`
+	Status           string `json:"status" bson:"status"`
+	Priority         string `json:"priority" bson:"priority" enum:"low,normal,high"` // defaults to normal; see priorityTweaks
+	Action           string `json:"action" bson:"action,omitempty"`                  // e.g. EMERGENCY; see selectionTweaks
 	ScheduledService struct {
-		IsScheduled     bool   `json:"isScheduled"`
-		ServiceCenterID string `json:"serviceCenterId"` // Maps to ID used in logic
-		DateTime        string `json:"dateTime"`
-	} `json:"scheduledService"`
+		IsScheduled     bool   `json:"isScheduled" bson:"isScheduled"`
+		ServiceCenterID string `json:"serviceCenterId" bson:"serviceCenterId"` // Maps to ID used in logic
+		DateTime        string `json:"dateTime" bson:"dateTime" format:"date-time"`
+	} `json:"scheduledService" bson:"scheduledService"`
+	Metadata map[string]string `json:"metadata" bson:"metadata,omitempty"` // free-form client key/values, stored verbatim
 }
 
 // Matches 'Bookings' schema in 'techathon_db'
 type DBBooking struct {
-	VehicleID        string           `json:"vehicleId" bson:"vehicleId"`
-	ConfirmationCode string           `json:"confirmationCode" bson:"confirmationCode"`
-	Status           string           `json:"status" bson:"status"`
-	ScheduledService ScheduledService `json:"scheduledService" bson:"scheduledService"`
-	UserID           string           `json:"userId,omitempty" bson:"userId,omitempty"`
+	VehicleID        string           `json:"vehicleId" bson:"vehicleId" xml:"vehicleId"`
+	Company          string           `json:"company,omitempty" bson:"company,omitempty" xml:"company,omitempty"` // from the vehicleId; older bookings need POST /internal/migrate/company
+	ConfirmationCode string           `json:"confirmationCode" bson:"confirmationCode" xml:"confirmationCode"`
+	Status           string           `json:"status" bson:"status" xml:"status"`
+	ScheduledService ScheduledService `json:"scheduledService" bson:"scheduledService" xml:"scheduledService"`
+	Priority         string           `json:"priority" bson:"priority" xml:"priority"`
+	Action           string           `json:"action,omitempty" bson:"action,omitempty" xml:"action,omitempty"`
+	Metadata         BookingMetadata  `json:"metadata,omitempty" bson:"metadata,omitempty" xml:"metadata,omitempty"`
+	UserID           string           `json:"userId" bson:"userId" xml:"userId"` // AnonymousUserID when there is no user
+	CreatedAt        time.Time        `json:"createdAt" bson:"createdAt" xml:"createdAt"`
+	UpdatedAt        time.Time        `json:"updatedAt" bson:"updatedAt" xml:"updatedAt"`
+	ReminderSentAt   *time.Time       `json:"reminderSentAt,omitempty" bson:"reminderSentAt,omitempty" xml:"reminderSentAt,omitempty"`
+	Notes            []Note           `json:"notes,omitempty" bson:"notes,omitempty" xml:"notes>note,omitempty"`
+	History          []ChangeEvent    `json:"history,omitempty" bson:"history,omitempty" xml:"history>change,omitempty"` // last BOOKING_HISTORY_LIMIT changes, oldest first
 }
 
+// AnonymousUserID is stored for bookings without a user, so "no user" is an
+// explicit value rather than a missing field. Bookings written before it
+// may still lack 'userId'; ?anonymous=true finds both.
+const AnonymousUserID = "anonymous"
+
 type ScheduledService struct {
-	IsScheduled     bool   `json:"isScheduled" bson:"isScheduled"`
-	ServiceCenterID string `json:"serviceCenterId" bson:"serviceCenterId"`
-	DateTime        string `json:"dateTime" bson:"dateTime"`
+	IsScheduled       bool   `json:"isScheduled" bson:"isScheduled" xml:"isScheduled"`
+	ServiceCenterID   string `json:"serviceCenterId" bson:"serviceCenterId" xml:"serviceCenterId"`
+	ServiceCenterName string `json:"serviceCenterName,omitempty" bson:"serviceCenterName,omitempty" xml:"serviceCenterName,omitempty"`
+	DateTime          string `json:"dateTime" bson:"dateTime" xml:"dateTime"`                               // always UTC
+	Timezone          string `json:"timezone,omitempty" bson:"timezone,omitempty" xml:"timezone,omitempty"` // the center's, when it has one
 }
 
 // Matches 'Logs' schema in 'techathon_db'
@@ -70,12 +91,13 @@ type LogData struct {
 
 // Matches 'service_centers' schema in 'auto_ai_db'
 type ServiceCenterDBModel struct {
-	ID       string        `json:"centerId" bson:"centerId"`
-	Name     string        `json:"name" bson:"name"`
-	Location string        `json:"location" bson:"location"`
-	Capacity int           `json:"capacity" bson:"capacity"`
-	Bookings []interface{} `json:"bookings" bson:"bookings"`
-	IsActive bool          `json:"is_active" bson:"is_active"`
+	ID       string          `json:"centerId" bson:"centerId"`
+	Name     string          `json:"name" bson:"name"`
+	Location string          `json:"location" bson:"location"`
+	Capacity *CenterCapacity `json:"capacity" bson:"capacity"` // nil when the admin side left it out
+	Timezone string          `json:"timezone" bson:"timezone"` // IANA name; empty means UTC
+	Bookings CenterBookings  `json:"bookings" bson:"bookings"`
+	IsActive bool            `json:"is_active" bson:"is_active"`
 }
 
 // --- 3. DATABASE SETUP ---
@@ -83,6 +105,7 @@ type ServiceCenterDBModel struct {
 var client *mongo.Client
 var bookingCollection *mongo.Collection
 var logsCollection *mongo.Collection
+var deadLettersCollection *mongo.Collection
 var serviceCenterCollection *mongo.Collection
 
 func main() {
@@ -90,24 +113,126 @@ func main() {
 		fmt.Println("No .env file found")
 	}
 
-	connectionString := os.Getenv("MONGO_URI")
-	dbName := os.Getenv("DB_NAME")
-	port := os.Getenv("PORT")
+	cfg := loadConfig()
+	d := newDeps(cfg)
+
+	shutdownTracing, err := initTracing(cfg)
+	if err != nil {
+		log.Fatal("Error setting up tracing:", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	if cfg.Store == StoreMemory {
+		store = newMemoryStore(cfg.BookingHistoryLimit)
+		fmt.Println("⚠️ STORE=memory: bookings and logs are kept in memory and lost on restart; MongoDB is not used")
+	} else {
+		connectMongo(cfg)
+		store = mongoStore{historyLimit: cfg.BookingHistoryLimit, writeRetries: cfg.MongoWriteRetries}
+		centerProvider = mongoCenterProvider{}
+	}
+	store = timedStore{store: store, backend: cfg.Store}
+	if cfg.CentersFile != "" {
+		provider, err := newFileCenterProvider(cfg.CentersFile)
+		if err != nil {
+			log.Fatal("Error loading service centers:", err)
+		}
+		centerProvider = provider
+		fmt.Printf("Serving %d service centers from %s\n", len(provider.centers), cfg.CentersFile)
+	}
+	if cfg.CenterSource == CenterSourceAdminAPI {
+		provider := newAdminAPICenterProvider(cfg)
+		centerProvider = provider
+		fmt.Printf("Reading service centers from %s and %d regional admin API routes\n", cfg.AdminAPIURL, len(cfg.AdminAPIRoutes))
+		if cfg.AdminAPIWakeUp {
+			go provider.wakeAll(context.Background())
+		}
+	}
 
-	if connectionString == "" {
-		log.Fatal("MONGO_URI is not set")
+	if cfg.WarmUpCenterLookup {
+		go warmUpCenterLookup(cfg, d.lookup)
 	}
-	if dbName == "" {
-		dbName = "techathon_db"
+	if cfg.StartupSelfTest && cfg.Store == StoreMongo {
+		go runStartupSelfTest(cfg)
 	}
-	if port == "" {
-		port = "8080"
+	if cfg.PendingTTL > 0 {
+		go runPendingExpiry(cfg)
 	}
+	if cfg.ReminderLead > 0 {
+		go runReminderScheduler(cfg, newNotifier(cfg.NotifyWebhookURL))
+	}
+
+	r := newRouter(cfg, d)
+	fmt.Println("Server starting on " + cfg.listenAddr() + "... (" + buildInfo() + ")")
+	r.Run(cfg.listenAddr())
+}
+
+// deps is the state main builds from Config once and shares with the router
+// and background jobs: things like the lookup throttle and circuit breaker
+// that must outlive a request but aren't plain settings.
+type deps struct {
+	lookup *centerLookup
+	health *healthRing
+}
+
+func newDeps(cfg Config) deps {
+	return deps{
+		lookup: newCenterLookup(cfg),
+		health: newHealthRing(cfg.HealthHistorySize),
+	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// newRouter builds the middleware chain and registers every route.
+func newRouter(cfg Config, d deps) *gin.Engine {
+	r := gin.New()
+	r.Use(accessLog(cfg), gin.Recovery())
+	// Before CORS, which answers preflights and rejected origins itself.
+	r.Use(securityHeaders())
+	r.Use(cors.New(corsConfig(cfg)))
+	r.Use(limitInFlight(cfg.MaxInFlight))
+	r.Use(otelgin.Middleware(tracingServiceName))
+	r.Use(responseEnvelope(cfg))
+
+	r.GET("/system-status", handleSystemStatus(cfg, d.lookup, d.health))
+	r.GET("/system-status/history", handleHealthHistory(d.health))
+	r.GET("/metrics", requireAdminToken(cfg), handleMetrics())
+	r.GET("/dead-letters", requireAdminToken(cfg), handleGetDeadLetters(cfg))
+	r.POST("/dead-letters/:id/replay", requireAdminToken(cfg), handleReplayDeadLetter(cfg, d.lookup))
+
+	r.GET("/internal/config", requireAdminToken(cfg), handleInternalConfig(cfg))
+	r.GET("/debug/centers", requireAdminToken(cfg), handleDebugCenters(cfg, d.lookup))
+	r.POST("/internal/migrate/company", requireAdminToken(cfg), requireMongoStore(cfg), handleMigrateCompany(cfg))
+	r.GET("/version", handleVersion)
+	r.GET("/schema/booking", handleBookingSchema)
+	r.GET("/validate-vehicle", handleValidateVehicle(cfg))
+	r.GET("/bookings", handleGetAllBookings(cfg))
+	r.GET("/bookings/autocomplete", handleBookingAutocomplete(cfg))
+	r.GET("/bookings/schedule", requireMongoStore(cfg), handleGetSchedule(cfg))
+	r.GET("/bookings/trends", requireMongoStore(cfg), handleBookingTrends(cfg))
+	r.POST("/bookings/bulk-cancel", requireAdminToken(cfg), requireJSON(), handleBulkCancel(cfg))
+	r.GET("/bookings/:confirmationCode", handleGetBooking(cfg))
+	r.GET("/bookings/:confirmationCode/reassign-options", requireMongoStore(cfg), handleReassignOptions(cfg, d.lookup))
+	r.POST("/bookings/:confirmationCode/sync", requireAdminToken(cfg), requireMongoStore(cfg), handleManualSync(cfg))
+	r.GET("/vehicles/:vehicleId/bookings", handleVehicleBookings(cfg))
+	r.GET("/logs", handleGetAllLogs(cfg))
+	r.GET("/logs/export", handleExportLogs(cfg))
+	r.POST("/logs", requireJSON(), requireMongoStore(cfg), handleCreateLog(cfg))
+	r.POST("/bookings/:confirmationCode/notes", requireJSON(), handleAddBookingNote(cfg))
+	r.POST("/book-service", requireJSON(), handleBooking(cfg, d.lookup))
+	r.POST("/book-service/check", requireJSON(), handleBookingCheck(cfg, d.lookup))
+	r.GET("/dashboard", requireMongoStore(cfg), handleDashboard(cfg, d.lookup))
+	r.GET("/centers", handleGetCenters(cfg, d.lookup))
+	r.GET("/centers/names", requireMongoStore(cfg), handleGetCenterNames(cfg))
+	r.GET("/centers/:centerId/bookings", handleGetCenterBookings(cfg))
+	return r
+}
+
+// connectMongo links the 'techathon_db' and 'auto_ai_db' collections, exiting
+// if the cluster can't be reached.
+func connectMongo(cfg Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoConnectTimeout)
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI(connectionString)
+	clientOptions := options.Client().ApplyURI(cfg.MongoURI).SetMonitor(mongoCommandMonitor())
 	var err error
 	client, err = mongo.Connect(ctx, clientOptions)
 	if err != nil {
@@ -121,91 +246,262 @@ func main() {
 	fmt.Println("Connected to MongoDB Cluster successfully!")
 
 	// 1. Access 'techathon_db'
-	techathonDB := client.Database(dbName)
-	bookingCollection = techathonDB.Collection("Bookings")
-	logsCollection = techathonDB.Collection("Logs")
-	fmt.Println("Linked to Database:", dbName)
+	techathonDB := client.Database(cfg.DBName)
+	bookingWriteConcern, _ = parseWriteConcern(cfg.WriteConcern) // validated in loadConfig
+	collectionOpts := options.Collection()
+	if bookingWriteConcern != nil {
+		collectionOpts.SetWriteConcern(bookingWriteConcern)
+		fmt.Println("Bookings/Logs write concern:", cfg.WriteConcern)
+	}
+	bookingCollection = techathonDB.Collection("Bookings", collectionOpts)
+	logsCollection = techathonDB.Collection("Logs", collectionOpts)
+	deadLettersCollection = techathonDB.Collection("DeadLetters")
+	logHashesCollection = techathonDB.Collection("LogHashes")
+	if cfg.LogContentDedupWindow > 0 {
+		ensureLogHashIndexes(ctx)
+	}
+	fmt.Println("Linked to Database:", cfg.DBName)
 
 	// 2. Access 'auto_ai_db' database
 	adminDB := client.Database("auto_ai_db")
 	serviceCenterCollection = adminDB.Collection("service_centers")
 	fmt.Println("Linked to Database: auto_ai_db (for service_centers updates)")
+}
+
+// --- 4. HANDLERS ---
+
+func handleGetAllLogs(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := parseListParams(c, "timestamp")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		q, err := logQueryFromRequest(c, list)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		total, err := store.CountLogs(ctx, q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count logs"})
+			return
+		}
+
+		if wantsNDJSON(c) {
+			setPaginationHeaders(c, total, list.Page)
+			streamLogsNDJSON(ctx, c, q)
+			return
+		}
+
+		logs := []LogEntry{}
+		err = store.EachLog(ctx, q, func(entry LogEntry) error {
+			logs = append(logs, entry)
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch logs"})
+			return
+		}
+		setPaginationHeaders(c, total, list.Page)
+		c.JSON(http.StatusOK, logs)
+	}
+}
+
+func handleGetAllBookings(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := parseListParams(c, "createdAt", "updatedAt")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	r := gin.Default()
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
-	r.Use(cors.New(config))
+		q := BookingQuery{
+			UserID:    c.Query("userId"),
+			Action:    normalizeAction(c.Query("action")),
+			Anonymous: c.Query("anonymous") == "true",
+			Status:    c.Query("status"),
+			Metadata:  metadataFilters(c),
+			// Orphans from older bugs: center missing, null, empty or the literal "null".
+			Unassigned: c.Query("unassigned") == "true",
+			List:       list,
+		}
 
-	r.GET("/system-status", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "Active"})
-	})
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
 
-	r.GET("/bookings", handleGetAllBookings)
-	r.GET("/logs", handleGetAllLogs)
-	r.POST("/book-service", handleBooking)
+		c.Header("Vary", "Accept")
+		if c.Query("stream") == "true" && !wantsXML(c) {
+			total, err := store.CountBookings(ctx, q)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch"})
+				return
+			}
+			setPaginationHeaders(c, total, list.Page)
+			streamBookingsJSON(ctx, c, q)
+			return
+		}
 
-	fmt.Println("Server starting on port " + port + "...")
-	r.Run(":" + port)
+		bookings, total, err := store.FindBookings(ctx, q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch"})
+			return
+		}
+		setPaginationHeaders(c, total, list.Page)
+		if wantsXML(c) {
+			c.XML(http.StatusOK, bookingListXML{Bookings: bookings})
+			return
+		}
+		// Dashboards poll this endpoint; let them skip unchanged payloads.
+		respondJSONWithETag(c, bookings)
+	}
 }
 
-// --- 4. HANDLERS ---
+// BookingOptions are the query switches of POST /book-service that change how
+// a booking is made. Dead letters keep them so a replay books the same way.
+type BookingOptions struct {
+	AllowPast bool `json:"allowPast,omitempty" bson:"allowPast,omitempty"` // admin backfill of a past time
+	SkipSync  bool `json:"skipSync,omitempty" bson:"skipSync,omitempty"`   // don't push to 'auto_ai_db', not even a slot reservation
+}
 
-func handleGetAllLogs(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+func handleBooking(cfg Config, lookup *centerLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// BOOKING_DEADLINE covers the whole call, so it starts before anything else.
+		deadline := time.Now().Add(cfg.BookingDeadline)
 
-	cursor, err := logsCollection.Find(ctx, bson.M{})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch logs"})
+		var req IncomingBookingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: " + err.Error()})
+			return
+		}
+
+		opts := BookingOptions{
+			AllowPast: c.Query("allowPast") == "true",
+			SkipSync:  c.Query("skipSync") == "true",
+		}
+		// Backfills of historical bookings may use past times; only admins can.
+		if opts.AllowPast && !hasAdminToken(cfg, c) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "allowPast requires the admin token", "code": "UNAUTHORIZED"})
+			return
+		}
+
+		bookService(c, cfg, lookup, req, opts, deadline)
+	}
+}
+
+// bookService validates and saves req, answering on c. It is the body of
+// POST /book-service, also called directly to replay a dead letter so the
+// request doesn't go through the middleware a second time. The deadline is
+// BOOKING_DEADLINE from when the call started.
+func bookService(c *gin.Context, cfg Config, lookup *centerLookup, req IncomingBookingRequest, opts BookingOptions, deadline time.Time) {
+	rules := cfg.capacityRules()
+
+	// --- CHECK COMPANY CONTRACT ---
+	if err := checkFieldLengths(cfg.MaxFieldLength, req.stringFields()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "FIELD_TOO_LONG"})
 		return
 	}
-	defer cursor.Close(ctx)
+	original := req // kept for dead letters, before normalization
 
-	var logs []LogEntry
-	if err = cursor.All(ctx, &logs); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding logs"})
+	company, problem := checkVehicleID(cfg, req.VehicleID)
+	if problem != nil {
+		c.JSON(problem.Status, gin.H{"error": problem.Reason, "code": problem.Code})
 		return
 	}
-	c.JSON(http.StatusOK, logs)
-}
+	if req.ConfirmationCode != "" {
+		if err := checkConfirmationCode(cfg, company, req.ConfirmationCode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_CONFIRMATION_CODE"})
+			return
+		}
+	}
 
-func handleGetAllBookings(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	cursor, err := bookingCollection.Find(ctx, bson.M{})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch"})
+	// --- VALIDATE SCHEDULED TIME ---
+	// isScheduled and dateTime have to agree: a scheduled booking needs a
+	// time and an unscheduled one must not carry one.
+	if req.ScheduledService.IsScheduled && req.ScheduledService.DateTime == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheduledService.dateTime is required when isScheduled is true", "code": "SCHEDULE_INCONSISTENT"})
 		return
 	}
-	defer cursor.Close(ctx)
-	var bookings []DBBooking
-	cursor.All(ctx, &bookings)
-	c.JSON(http.StatusOK, bookings)
-}
+	if !req.ScheduledService.IsScheduled && req.ScheduledService.DateTime != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheduledService.dateTime must be empty when isScheduled is false", "code": "SCHEDULE_INCONSISTENT"})
+		return
+	}
+
+	// Clients send a mix of formats; normalize everything to RFC3339 UTC before storing.
+	var scheduledAt time.Time
+	if req.ScheduledService.DateTime != "" {
+		var err error
+		scheduledAt, err = parseFlexibleTime(req.ScheduledService.DateTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduledService.dateTime: " + err.Error()})
+			return
+		}
+		req.ScheduledService.DateTime = formatTimestamp(scheduledAt)
+	}
+
+	priority, ok := normalizePriority(req.Priority)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority: must be one of low, normal, high"})
+		return
+	}
+	req.Priority = priority
+	req.Action = normalizeAction(req.Action)
+	status, ok := normalizeBookingStatus(req.Status)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status: must be one of PENDING, CONFIRMED", "code": "INVALID_STATUS"})
+		return
+	}
+	req.Status = status
 
-func handleBooking(c *gin.Context) {
-	var req IncomingBookingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: " + err.Error()})
+	if err := validateMetadata(req.Metadata); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metadata: " + err.Error()})
 		return
 	}
 
 	// Generate a Log ID immediately (needed for response even if rejected)
-	randNum := rand.Intn(10000)
-	currentLogID := fmt.Sprintf("LOG_%s_%04d", time.Now().Format("20060102"), randNum)
+	currentLogID := generateLogID(cfg)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithDeadline(context.WithoutCancel(c.Request.Context()), deadline)
 	defer cancel()
 
 	// --- CHECK EXISTING BOOKING ---
-	var existingBooking DBBooking
-	err := bookingCollection.FindOne(ctx, bson.M{"vehicleId": req.VehicleID}).Decode(&existingBooking)
+	// Cancelled and completed bookings don't count; the vehicle can book again.
+	existingBooking, err := store.FindActiveBooking(ctx, req.VehicleID)
 
 	isUpdate := false // Flag to track if we are updating or inserting
 
 	if err == nil {
 		// Found existing booking
+		if isRecentDuplicate(cfg, existingBooking, req, scheduledAt) {
+			// SCENARIO: Same request again within the dedup window -> return the first
+			fmt.Printf("⚠️ Duplicate booking request for %s within %s, returning existing booking\n", req.VehicleID, cfg.DedupWindow)
+			dedupLog := bookingLogEntry(cfg, existingBooking, "DEDUPED_BOOKING")
+			dedupLog.LogID = currentLogID
+			if err := store.InsertLog(ctx, dedupLog); err != nil {
+				fmt.Println("Error saving log:", err)
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"assignedCenter":   existingBooking.ScheduledService.ServiceCenterID,
+				"bookingStatus":    existingBooking.Status,
+				"confirmationCode": existingBooking.ConfirmationCode,
+				"generatedLogId":   currentLogID,
+				"message":          "duplicate request, returning the existing booking",
+				"deduplicated":     true,
+			})
+			return
+		}
+		if existingBooking.ScheduledService.IsScheduled && cfg.SingleActiveBookingPerVehicle {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":           "Vehicle already has an active booking",
+				"code":            "ACTIVE_BOOKING_EXISTS",
+				"existingBooking": existingBooking,
+			})
+			return
+		}
 		if existingBooking.ScheduledService.IsScheduled {
 			// SCENARIO: Entry exists AND isScheduled is TRUE -> Return "already booked"
 			c.JSON(http.StatusOK, gin.H{
@@ -220,9 +516,12 @@ func handleBooking(c *gin.Context) {
 			fmt.Printf("⚠️ Booking exists for %s but not scheduled. Updating entry...\n", req.VehicleID)
 			isUpdate = true
 		}
-	} else if err == mongo.ErrNoDocuments {
+	} else if err == errBookingNotFound {
 		// SCENARIO: No entry exists -> Create new
 		isUpdate = false
+	} else if pastDeadline(ctx, err) {
+		respondDeadlineExceeded(cfg, c)
+		return
 	} else {
 		// Real DB Error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB Error checking existence"})
@@ -232,50 +531,77 @@ func handleBooking(c *gin.Context) {
 	// --- LOGIC TO DETERMINE CENTER ID (Runs for both New and Update scenarios) ---
 	finalCenterID := req.ScheduledService.ServiceCenterID
 	isAutoAssigned := false
+	usedFallback := false
+	overbooked := false
+	var candidates []*ServiceCenterDBModel
+	var consideredCenters []ServiceCenterDBModel // kept for ?explain=true
 
 	if finalCenterID == "" || finalCenterID == "null" {
 		fmt.Println("⚠️ Center ID missing. Querying DB for least busy center...")
 
-		filter := bson.M{"is_active": true}
-		cursor, err := serviceCenterCollection.Find(ctx, filter)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query service centers"})
+		// Inactive centers are fetched too so an empty selection can be explained.
+		centers, err := lookup.centers(ctx, company)
+		if errors.Is(err, errCenterLookupBusy) {
+			// Our own throttling: the client just retries, so there is
+			// nothing to dead-letter or waitlist, even near the deadline.
+			respondCenterLookupError(c, err)
 			return
 		}
-		defer cursor.Close(ctx)
-
-		var centers []ServiceCenterDBModel
-		if err = cursor.All(ctx, &centers); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode service centers"})
+		if pastDeadline(ctx, err) {
+			respondBookingDeadline(ctx, cfg, c, company, req, original, opts, waitlistBefore(isUpdate, existingBooking), currentLogID, err)
+			return
+		}
+		if err != nil {
+			recordDeadLetter(ctx, cfg, DeadLetterSelection, original, opts, nil, err)
+			respondCenterLookupError(c, err)
 			return
 		}
 
-		var bestCenter *ServiceCenterDBModel
-		minBookings := 999999
-
-		for i := range centers {
-			if centers[i].ID == "" {
+		_, selectSpan := tracer.Start(ctx, "selectCenter")
+		candidates = rules.rankCenters(centers, scheduledAt, preferredCenters(cfg, company))
+		consideredCenters = centers
+		selectSpan.SetAttributes(attribute.Int("centers.fetched", len(centers)), attribute.Int("centers.candidates", len(candidates)))
+		selectSpan.End()
+		for _, center := range centers {
+			if rules.centerExclusion(center, scheduledAt) != excludedUnnamed {
 				continue
 			}
-			currentLoad := len(centers[i].Bookings)
-			if currentLoad < minBookings {
-				minBookings = currentLoad
-				bestCenter = &centers[i]
+			fmt.Printf("⚠️ Skipping service center %s for %s: it has no name\n", center.ID, req.VehicleID)
+			skipped := DBBooking{VehicleID: req.VehicleID, UserID: "USR_" + req.VehicleID, ScheduledService: ScheduledService{ServiceCenterID: center.ID}}
+			if err := store.InsertLog(ctx, bookingLogEntry(cfg, skipped, "SKIPPED_UNNAMED_CENTER")); err != nil {
+				fmt.Println("Error saving log:", err)
 			}
 		}
-
-		if bestCenter == nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "No valid service centers available"})
+		if len(candidates) > 0 {
+			finalCenterID = candidates[0].ID
+			isAutoAssigned = true
+		} else if overbook := rules.leastBookedCenter(centers, scheduledAt); overbook != nil && tweaksFor(req.Action, req.Priority).AllowOverbooking {
+			// Booked like a client-chosen center, so no capacity check applies.
+			fmt.Printf("⚠️ No center available for %s %s booking (priority %s), overbooking %s\n", req.VehicleID, req.Action, req.Priority, overbook.ID)
+			finalCenterID = overbook.ID
+			overbooked = true
+		} else if fallbackID, ok := fallbackCenter(cfg, company); ok {
+			// The company's overflow center takes the booking even when it is
+			// over capacity, so it is booked like a client-chosen center.
+			fmt.Printf("⚠️ No center available for %s, using %s's fallback center %s\n", req.VehicleID, company, fallbackID)
+			finalCenterID = fallbackID
+			usedFallback = true
+		} else {
+			respondNoCenterSelected(c, rules, centers, scheduledAt)
 			return
 		}
-
-		finalCenterID = bestCenter.ID
-		isAutoAssigned = true
 	}
 
 	// --- PREPARE DATA ---
+	if req.ConfirmationCode == "" && !isUpdate {
+		if code, ok := newConfirmationCode(cfg, company); ok {
+			req.ConfirmationCode = code
+		}
+	}
+	now := time.Now().UTC()
 	bookingData := DBBooking{
 		VehicleID:        req.VehicleID,
+		Company:          company,
 		ConfirmationCode: req.ConfirmationCode,
 		Status:           req.Status,
 		ScheduledService: ScheduledService{
@@ -283,33 +609,91 @@ func handleBooking(c *gin.Context) {
 			ServiceCenterID: finalCenterID,
 			DateTime:        req.ScheduledService.DateTime,
 		},
-		UserID: "USR_" + req.VehicleID,
+		Priority:  req.Priority,
+		Action:    req.Action,
+		Metadata:  req.Metadata,
+		UserID:    "USR_" + req.VehicleID,
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
-	// --- EXECUTE DB WRITE (INSERT OR UPDATE) ---
-	if isUpdate {
-		// Update existing document
-		filter := bson.M{"vehicleId": req.VehicleID}
-		update := bson.M{
-			"$set": bson.M{
-				"confirmationCode": bookingData.ConfirmationCode,
-				"status":           bookingData.Status,
-				"scheduledService": bookingData.ScheduledService,
-				"userId":           bookingData.UserID,
-			},
-		}
-		_, err := bookingCollection.UpdateOne(ctx, filter, update)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
+	// The past-time check runs once the time is localized to the center,
+	// since a time without an offset means something different per center.
+	isBackfill := false
+	notInPast := func() bool {
+		if !scheduledInPast(bookingData.ScheduledService.DateTime, cfg.PastBookingGrace) {
+			return true
+		}
+		if opts.AllowPast {
+			isBackfill = true
+			return true
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheduledService.dateTime is in the past", "code": "SCHEDULED_IN_PAST"})
+		return false
+	}
+
+	// A requested time without an offset is local time at the center.
+	if !isAutoAssigned {
+		center := lookup.centerDetails(ctx, finalCenterID)
+		bookingData.ScheduledService.ServiceCenterName = center.Name
+		bookingData.ScheduledService.localizeTo(original.ScheduledService.DateTime, center.Timezone)
+		if !notInPast() {
 			return
 		}
-	} else {
-		// Insert new document
-		_, err := bookingCollection.InsertOne(ctx, bookingData)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+	}
+
+	// Backfills skip the push so importing history doesn't flood the
+	// center side; POST /bookings/:code/sync can push them later. For an
+	// auto-assigned booking the push is the slot reservation itself, so a
+	// skipped sync leaves it placed by the capacity read during ranking.
+	syncSkipped := cfg.DisableRemoteSync || opts.SkipSync
+
+	// --- RESERVE A SLOT (auto-assigned only) ---
+	// Capacity was read a moment ago and may already be stale, so the slot is
+	// claimed atomically. If the best center filled up meanwhile, move down
+	// the ranking, up to MAX_CENTER_ATTEMPTS centers, before giving up.
+	retriedAfterConflict := false
+	rejectedCenters := []string{}
+	var assignedCenter *ServiceCenterDBModel // as ranked, before this booking
+	if isAutoAssigned {
+		reserved := false
+		for attempt, center := range candidates[:min(cfg.MaxCenterAttempts, len(candidates))] {
+			bookingData.ScheduledService.ServiceCenterID = center.ID
+			bookingData.ScheduledService.ServiceCenterName = center.Name
+			bookingData.ScheduledService.Timezone = ""
+			bookingData.ScheduledService.localizeTo(original.ScheduledService.DateTime, center.Timezone)
+			if !notInPast() {
+				return
+			}
+			ok, err := true, error(nil)
+			if !syncSkipped {
+				ok, err = reserveCenterSlot(ctx, rules, center.ID, bookingData)
+			}
+			if pastDeadline(ctx, err) {
+				respondBookingDeadline(ctx, cfg, c, company, req, original, opts, waitlistBefore(isUpdate, existingBooking), currentLogID, err)
+				return
+			}
+			if err != nil {
+				fmt.Println("❌ Slot reservation failed:", err)
+				recordDeadLetter(ctx, cfg, DeadLetterSelection, original, opts, nil, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve service center slot"})
+				return
+			}
+			if ok {
+				reserved = true
+				assignedCenter = center
+				retriedAfterConflict = attempt > 0
+				break
+			}
+			fmt.Printf("⚠️ Center %s filled up before %s could be placed (attempt %d of %d)\n", center.ID, req.VehicleID, attempt+1, min(cfg.MaxCenterAttempts, len(candidates)))
+			rejectedCenters = append(rejectedCenters, center.ID)
+		}
+		if !reserved {
+			recordDeadLetter(ctx, cfg, DeadLetterSelection, original, opts, nil, fmt.Errorf("centers %v filled up while booking", rejectedCenters))
+			c.JSON(http.StatusConflict, gin.H{"error": "Service centers filled up while booking, please retry", "code": "CENTER_CAPACITY_CONFLICT", "rejectedCenters": rejectedCenters})
 			return
 		}
+		finalCenterID = bookingData.ScheduledService.ServiceCenterID
 	}
 
 	// --- LOGGING ---
@@ -317,44 +701,151 @@ func handleBooking(c *gin.Context) {
 		LogID:     currentLogID,
 		UserID:    bookingData.UserID,
 		VehicleID: req.VehicleID,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Timestamp: formatTimestamp(time.Now()),
 		LogType:   "BOOKING",
 		Data: LogData{
 			ConfirmationCode: req.ConfirmationCode,
-			Status:           req.Status,
+			Status:           bookingData.Status,
 			ServiceCenterID:  finalCenterID,
-			ScheduledAt:      req.ScheduledService.DateTime,
+			ScheduledAt:      bookingData.ScheduledService.DateTime,
 			IsScheduled:      req.ScheduledService.IsScheduled,
 			Action:           "CREATED",
 		},
 	}
-	if isUpdate {
+	if isBackfill {
+		logEntry.Data.Action = "BACKFILL_BOOKING"
+	} else if overbooked {
+		logEntry.Data.Action = "OVERBOOKED_CENTER"
+	} else if usedFallback {
+		logEntry.Data.Action = "ASSIGNED_FALLBACK_CENTER"
+	} else if isUpdate {
 		logEntry.Data.Action = "UPDATED_SCHEDULE"
+	} else if retriedAfterConflict {
+		logEntry.Data.Action = "RETRIED_AFTER_CONFLICT"
 	} else if isAutoAssigned {
 		logEntry.Data.Action = "AUTO_ASSIGNED_CREATED"
 	}
-	logsCollection.InsertOne(ctx, logEntry)
-
-	// --- UPDATE EXTERNAL DB (Background) ---
-	go func() {
-		bgCtx, bgCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer bgCancel()
 
-		fmt.Printf("🔄 Updating 'auto_ai_db' -> Center: %s\n", finalCenterID)
-		filter := bson.M{"centerId": finalCenterID}
-		update := bson.M{"$push": bson.M{"bookings": bookingData}}
+	// --- EXECUTE DB WRITES (BOOKING INSERT OR UPDATE + LOG, ATOMICALLY) ---
+	before := DBBooking{}
+	if isUpdate {
+		before = existingBooking
+	}
+	changes := bookingChanges(before, bookingData, withUserID(bookingData).UserID, now)
+	logPersisted, err := store.SaveBookingWithLog(ctx, bookingData, isUpdate, changes, logEntry)
+	if err != nil {
+		fmt.Println("❌ Booking write failed:", err)
+		// The deadline may be what failed the write; cleanup still gets to run.
+		cleanupCtx, cleanupCancel := context.WithTimeout(context.WithoutCancel(ctx), deadlineWriteGrace)
+		defer cleanupCancel()
+		recordDeadLetter(cleanupCtx, cfg, DeadLetterWrite, original, opts, nil, err)
+		if isAutoAssigned && !syncSkipped {
+			if err := releaseCenterSlot(cleanupCtx, bookingData); err != nil {
+				fmt.Printf("⚠️ Could not release reserved slot at %s: %v\n", finalCenterID, err)
+			}
+		}
+		if pastDeadline(ctx, err) {
+			respondDeadlineExceeded(cfg, c)
+		} else if isUpdate {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		}
+		return
+	}
 
-		_, err := serviceCenterCollection.UpdateOne(bgCtx, filter, update)
+	// --- UPDATE EXTERNAL DB (Background, explicitly chosen centers only) ---
+	if syncSkipped {
+		skipLog := bookingLogEntry(cfg, bookingData, "SYNC_SKIPPED")
+		if err := store.InsertLog(ctx, skipLog); err != nil {
+			fmt.Println("Error saving log:", err)
+		}
+	} else if !isAutoAssigned && requiresSync(cfg, company) {
+		syncCtx, syncCancel := context.WithTimeout(ctx, cfg.CenterSyncTimeout)
+		err := updateRemoteServiceCenter(syncCtx, bookingData)
+		syncCancel()
 		if err != nil {
-			fmt.Printf("❌ DB Update Failed: %v\n", err)
+			respondRequiredSyncFailed(ctx, cfg, c, original, opts, bookingData, err)
+			return
 		}
-	}()
+	} else if !isAutoAssigned {
+		go func() {
+			bgCtx, bgCancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.CenterSyncTimeout)
+			defer bgCancel()
+
+			fmt.Printf("🔄 Updating 'auto_ai_db' -> Center: %s\n", finalCenterID)
+			if err := updateRemoteServiceCenter(bgCtx, bookingData); err != nil {
+				fmt.Printf("❌ DB Update Failed: %v\n", err)
+				// Without 'auto_ai_db' (STORE=memory) there is nothing to replay against.
+				if !errors.Is(err, errCenterSourceUnavailable) {
+					recordDeadLetter(bgCtx, cfg, DeadLetterSync, original, opts, &bookingData, err)
+				}
+			}
+		}()
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("booking.vehicle_id", req.VehicleID),
+		attribute.String("booking.center_id", finalCenterID),
+		attribute.Bool("booking.auto_assigned", isAutoAssigned),
+	)
 
 	// Response
-	c.JSON(http.StatusOK, gin.H{
-		"bookingStatus":  "Confirmed",
+	response := gin.H{
+		"bookingStatus":  bookingData.Status,
 		"generatedLogId": currentLogID,
 		"assignedCenter": finalCenterID,
 		"message":        "Successfully saved",
-	})
-}
\ No newline at end of file
+		"logPersisted":   logPersisted,
+		"fallbackUsed":   usedFallback,
+	}
+	if overbooked {
+		response["overbooked"] = true
+	}
+	// Only auto-assigned centers were read with their capacity; the count
+	// is as of selection, so concurrent bookings may have taken more.
+	if assignedCenter != nil {
+		if free := rules.freeSlots(*assignedCenter, scheduledAt); free != unlimitedSlots {
+			response["centerFreeSlotsAfter"] = max(free-1, 0)
+		}
+	}
+	if syncSkipped {
+		response["syncSkipped"] = true
+	}
+	if bookingData.ConfirmationCode != "" {
+		response["confirmationCode"] = bookingData.ConfirmationCode
+	}
+	if tz := bookingData.ScheduledService.Timezone; tz != "" {
+		response["centerTimezone"] = tz
+		if bookingData.ScheduledService.DateTime != "" {
+			response["localDateTime"] = bookingData.ScheduledService.LocalDateTime()
+		}
+	}
+	if len(rejectedCenters) > 0 {
+		response["rejectedCenters"] = rejectedCenters
+	}
+	if !logPersisted {
+		response["warning"] = "Booking saved but its audit log entry could not be written"
+	}
+	if c.Query("explain") == "true" {
+		if isAutoAssigned {
+			selection := rules.explainSelection(consideredCenters, candidates, scheduledAt, finalCenterID)
+			selection["retriedAfterConflict"] = retriedAfterConflict
+			response["selection"] = selection
+		} else if usedFallback {
+			selection := rules.explainSelection(consideredCenters, candidates, scheduledAt, finalCenterID)
+			selection["strategy"] = "company fallback center"
+			response["selection"] = selection
+		} else {
+			response["selection"] = gin.H{"strategy": "chosen by client", "chosen": finalCenterID}
+		}
+	}
+	if isUpdate {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if bookingData.ConfirmationCode != "" {
+		c.Header("Location", bookingLocation(bookingData.ConfirmationCode))
+	}
+	c.JSON(http.StatusCreated, response)
+}
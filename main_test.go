@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeCenterProvider serves a fixed center list, like CENTERS_FILE does.
+type fakeCenterProvider struct {
+	centers []ServiceCenterDBModel
+}
+
+func (p fakeCenterProvider) CentersForCompany(context.Context, string) ([]ServiceCenterDBModel, error) {
+	return append([]ServiceCenterDBModel(nil), p.centers...), nil
+}
+
+func testCenter(id string, capacity int, bookings int) ServiceCenterDBModel {
+	c := CenterCapacity(capacity)
+	center := ServiceCenterDBModel{ID: id, Name: "Center " + id, Capacity: &c, IsActive: true}
+	for i := 0; i < bookings; i++ {
+		center.Bookings = append(center.Bookings, map[string]interface{}{"vehicleId": "OTHER_" + id})
+	}
+	return center
+}
+
+// testConfig is the STORE=memory configuration with every default applied.
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	t.Setenv("STORE", StoreMemory)
+	return loadConfig()
+}
+
+// newTestRouter points the store and center source at in-memory fakes for
+// the length of the test and returns the service's router.
+func newTestRouter(t *testing.T, cfg Config, centers ...ServiceCenterDBModel) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	prevStore, prevProvider := store, centerProvider
+	t.Cleanup(func() { store, centerProvider = prevStore, prevProvider })
+	store = newMemoryStore(cfg.BookingHistoryLimit)
+	centerProvider = fakeCenterProvider{centers: centers}
+	return newRouter(cfg, newDeps(cfg))
+}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+}
+
+const autoBooking = `{"vehicleId":"ACME_1","scheduledService":{"isScheduled":true,"dateTime":"2030-01-01T10:00:00Z"}}`
+
+func TestSystemStatus(t *testing.T) {
+	r := newTestRouter(t, testConfig(t))
+
+	w := serve(r, http.MethodGet, "/system-status", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var body struct {
+		Status   string `json:"status"`
+		Database bool   `json:"database"`
+	}
+	decode(t, w, &body)
+	if body.Status != "Active" || !body.Database {
+		t.Errorf("got %+v, want an active service with its database up", body)
+	}
+}
+
+func TestBookServiceAutoAssigns(t *testing.T) {
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 2, 1), testCenter("C2", 2, 0))
+
+	w := serve(r, http.MethodPost, "/book-service", autoBooking)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var body struct {
+		AssignedCenter string `json:"assignedCenter"`
+		BookingStatus  string `json:"bookingStatus"`
+	}
+	decode(t, w, &body)
+	if body.AssignedCenter != "C2" || body.BookingStatus != StatusConfirmed {
+		t.Errorf("got %+v, want a confirmed booking at the emptier C2", body)
+	}
+}
+
+func TestBookServiceActiveBookingConflict(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SingleActiveBookingPerVehicle = true
+	r := newTestRouter(t, cfg, testCenter("C1", 5, 0))
+
+	if w := serve(r, http.MethodPost, "/book-service", autoBooking); w.Code != http.StatusCreated {
+		t.Fatalf("first booking: status = %d, body %s", w.Code, w.Body)
+	}
+	w := serve(r, http.MethodPost, "/book-service", autoBooking)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("second booking: status = %d, body %s", w.Code, w.Body)
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &body)
+	if body.Code != "ACTIVE_BOOKING_EXISTS" {
+		t.Errorf("code = %q, want ACTIVE_BOOKING_EXISTS", body.Code)
+	}
+}
+
+func TestBookServiceAllCentersFull(t *testing.T) {
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 1, 1), testCenter("C2", 2, 2))
+
+	w := serve(r, http.MethodPost, "/book-service", autoBooking)
+	var body struct {
+		Code string `json:"code"`
+	}
+	decode(t, w, &body)
+	if w.Code != http.StatusConflict || body.Code != "ALL_CENTERS_FULL" {
+		t.Errorf("status = %d, code = %q, want 409 ALL_CENTERS_FULL", w.Code, body.Code)
+	}
+}
+
+func TestGetBookingsAndLogs(t *testing.T) {
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 5, 0))
+
+	w := serve(r, http.MethodGet, "/bookings", "")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("empty store: status = %d, body %s", w.Code, w.Body)
+	}
+
+	if w := serve(r, http.MethodPost, "/book-service", autoBooking); w.Code != http.StatusCreated {
+		t.Fatalf("booking: status = %d, body %s", w.Code, w.Body)
+	}
+
+	w = serve(r, http.MethodGet, "/bookings", "")
+	var bookings []DBBooking
+	decode(t, w, &bookings)
+	if len(bookings) != 1 || bookings[0].VehicleID != "ACME_1" || bookings[0].ScheduledService.ServiceCenterID != "C1" {
+		t.Fatalf("bookings = %+v, want ACME_1 at C1", bookings)
+	}
+	if got := w.Header().Get("X-Total-Count"); got != "1" {
+		t.Errorf("X-Total-Count = %q, want 1", got)
+	}
+
+	w = serve(r, http.MethodGet, "/logs", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("logs: status = %d, body %s", w.Code, w.Body)
+	}
+	var logs []LogEntry
+	decode(t, w, &logs)
+	if len(logs) != 1 || logs[0].VehicleID != "ACME_1" || logs[0].Data.Action != "AUTO_ASSIGNED_CREATED" {
+		t.Errorf("logs = %+v, want one AUTO_ASSIGNED_CREATED entry for ACME_1", logs)
+	}
+}
+
+func TestLogStatusMatchesSavedBooking(t *testing.T) {
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 5, 0))
+
+	for _, body := range []string{
+		`{"vehicleId":"ACME_1","scheduledService":{"isScheduled":true,"dateTime":"2030-01-01T10:00:00Z"}}`,
+		`{"vehicleId":"ACME_2","status":"PENDING","scheduledService":{"isScheduled":true,"dateTime":"2030-01-01T10:00:00Z"}}`,
+	} {
+		if w := serve(r, http.MethodPost, "/book-service", body); w.Code != http.StatusCreated {
+			t.Fatalf("booking %s: status = %d, body %s", body, w.Code, w.Body)
+		}
+	}
+
+	var bookings []DBBooking
+	decode(t, serve(r, http.MethodGet, "/bookings", ""), &bookings)
+	var logs []LogEntry
+	decode(t, serve(r, http.MethodGet, "/logs", ""), &logs)
+
+	saved := map[string]string{}
+	for _, b := range bookings {
+		saved[b.VehicleID] = b.Status
+	}
+	if saved["ACME_1"] != StatusConfirmed || saved["ACME_2"] != StatusPending {
+		t.Fatalf("saved statuses = %v, want ACME_1 CONFIRMED and ACME_2 PENDING", saved)
+	}
+	if len(logs) != len(bookings) {
+		t.Fatalf("got %d logs for %d bookings", len(logs), len(bookings))
+	}
+	for _, entry := range logs {
+		if entry.Data.Status != saved[entry.VehicleID] {
+			t.Errorf("log for %s says %q, booking is %q", entry.VehicleID, entry.Data.Status, saved[entry.VehicleID])
+		}
+	}
+}
+
+func TestBookServiceSkipSyncAutoAssigned(t *testing.T) {
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 5, 0))
+
+	w := serve(r, http.MethodPost, "/book-service?skipSync=true", autoBooking)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var body struct {
+		AssignedCenter string `json:"assignedCenter"`
+		SyncSkipped    bool   `json:"syncSkipped"`
+	}
+	decode(t, w, &body)
+	if body.AssignedCenter != "C1" || !body.SyncSkipped {
+		t.Errorf("got %+v, want C1 with syncSkipped", body)
+	}
+
+	var logs []LogEntry
+	decode(t, serve(r, http.MethodGet, "/logs", ""), &logs)
+	actions := map[string]bool{}
+	for _, entry := range logs {
+		actions[entry.Data.Action] = true
+	}
+	if !actions["AUTO_ASSIGNED_CREATED"] || !actions["SYNC_SKIPPED"] {
+		t.Errorf("log actions = %v, want AUTO_ASSIGNED_CREATED and SYNC_SKIPPED", actions)
+	}
+}
+
+func TestConcurrentDeadLetterReplaysBookOnce(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "s3cret")
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 5, 0))
+	var req IncomingBookingRequest
+	if err := json.Unmarshal([]byte(autoBooking), &req); err != nil {
+		t.Fatal(err)
+	}
+	dl := DeadLetter{ID: "DL_1", Stage: DeadLetterSelection, Reason: "test", Request: req}
+	if err := store.SaveDeadLetter(context.Background(), dl); err != nil {
+		t.Fatal(err)
+	}
+
+	const replays = 8
+	codes := make(chan int, replays)
+	var wg sync.WaitGroup
+	for i := 0; i < replays; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/dead-letters/DL_1/replay", nil)
+			req.Header.Set("Authorization", "Bearer s3cret")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusCreated] != 1 || counts[http.StatusConflict] != replays-1 {
+		t.Errorf("replay statuses = %v, want one 201 and the rest 409", counts)
+	}
+	var bookings []DBBooking
+	decode(t, serve(r, http.MethodGet, "/bookings", ""), &bookings)
+	if len(bookings) != 1 {
+		t.Errorf("got %d bookings, want 1", len(bookings))
+	}
+}
+
+func TestBookServiceNormalizesStatus(t *testing.T) {
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 10, 0))
+
+	for i, tc := range []struct {
+		status, want string
+	}{
+		{status: "pending", want: StatusPending},
+		{status: "Pending ", want: StatusPending},
+		{status: " confirmed", want: StatusConfirmed},
+		{status: "", want: StatusConfirmed},
+	} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"vehicleId":        fmt.Sprintf("ACME_%d", i),
+			"status":           tc.status,
+			"scheduledService": map[string]interface{}{"isScheduled": true, "dateTime": "2030-01-01T10:00:00Z"},
+		})
+		w := serve(r, http.MethodPost, "/book-service", string(body))
+		var resp struct {
+			BookingStatus string `json:"bookingStatus"`
+		}
+		decode(t, w, &resp)
+		if w.Code != http.StatusCreated || resp.BookingStatus != tc.want {
+			t.Errorf("status %q: got %d %q, want 201 %q", tc.status, w.Code, resp.BookingStatus, tc.want)
+		}
+	}
+
+	for _, status := range []string{"CANCELLED", "SYNC_PENDING", "booked"} {
+		w := serve(r, http.MethodPost, "/book-service", `{"vehicleId":"ACME_9","status":"`+status+`"}`)
+		var resp struct {
+			Code string `json:"code"`
+		}
+		decode(t, w, &resp)
+		if w.Code != http.StatusBadRequest || resp.Code != "INVALID_STATUS" {
+			t.Errorf("status %q: got %d %q, want 400 INVALID_STATUS", status, w.Code, resp.Code)
+		}
+	}
+}
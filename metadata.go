@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits on client supplied booking metadata.
+const (
+	maxMetadataKeys      = 20
+	maxMetadataKeyLength = 64
+	maxMetadataBytes     = 4096
+)
+
+// validateMetadata enforces the size limits and keeps keys safe to use as
+// Mongo field names (no dots, no leading '$').
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataKeys {
+		return fmt.Errorf("metadata may have at most %d keys", maxMetadataKeys)
+	}
+	total := 0
+	for key, value := range metadata {
+		if key == "" || len(key) > maxMetadataKeyLength {
+			return fmt.Errorf("metadata keys must be 1-%d characters", maxMetadataKeyLength)
+		}
+		if strings.Contains(key, ".") || strings.HasPrefix(key, "$") {
+			return fmt.Errorf("metadata key %q may not contain '.' or start with '$'", key)
+		}
+		total += len(key) + len(value)
+	}
+	if total > maxMetadataBytes {
+		return fmt.Errorf("metadata may total at most %d bytes", maxMetadataBytes)
+	}
+	return nil
+}
+
+// metadataFilters turns ?metadata.<key>=<value> query params into exact
+// match conditions on the booking's metadata.
+func metadataFilters(c *gin.Context) map[string]string {
+	filters := map[string]string{}
+	for param, values := range c.Request.URL.Query() {
+		key, ok := strings.CutPrefix(param, "metadata.")
+		if !ok || key == "" || strings.HasPrefix(key, "$") || len(values) == 0 {
+			continue
+		}
+		filters[key] = values[0]
+	}
+	return filters
+}
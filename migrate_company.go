@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- COMPANY BACKFILL ---
+
+const (
+	defaultMigrationBatchSize = 500
+	maxMigrationBatchSize     = 1000
+	defaultMigrationBatches   = 20
+)
+
+// withoutCompany matches bookings written before 'company' was stored.
+var withoutCompany = bson.M{"company": bson.M{"$exists": false}}
+
+// CompanyMigrationProgress is what one POST /internal/migrate/company run did.
+type CompanyMigrationProgress struct {
+	Scanned    int   `json:"scanned"`
+	Updated    int   `json:"updated"`
+	Unresolved int   `json:"unresolved"` // vehicleId without a company; stored as "" so they aren't rescanned
+	Remaining  int64 `json:"remaining"`
+	Done       bool  `json:"done"`
+}
+
+// handleMigrateCompany backfills 'company' on bookings that lack it, derived
+// from the vehicleId, in up to ?maxBatches= batches of ?batchSize=. Only
+// bookings still without the field are touched, so the migration can be
+// called again until "done" and re-running it is harmless.
+func handleMigrateCompany(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batchSize, err := migrationParam(c, "batchSize", defaultMigrationBatchSize, maxMigrationBatchSize)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		maxBatches, err := migrationParam(c, "maxBatches", defaultMigrationBatches, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		var progress CompanyMigrationProgress
+		for batch := 0; batch < maxBatches; batch++ {
+			scanned, err := migrateCompanyBatch(ctx, batchSize, &progress)
+			if err != nil {
+				fmt.Println("❌ Company migration failed:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Company migration failed, call again to resume", "progress": progress})
+				return
+			}
+			if scanned < batchSize {
+				break
+			}
+		}
+
+		remaining, err := bookingCollection.CountDocuments(ctx, withoutCompany)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count remaining bookings", "progress": progress})
+			return
+		}
+		progress.Remaining = remaining
+		progress.Done = remaining == 0
+		fmt.Printf("🔄 Company migration: %d updated, %d unresolved, %d remaining\n", progress.Updated, progress.Unresolved, remaining)
+		c.JSON(http.StatusOK, progress)
+	}
+}
+
+// migrateCompanyBatch sets 'company' on up to size bookings and reports how
+// many it looked at.
+func migrateCompanyBatch(ctx context.Context, size int, progress *CompanyMigrationProgress) (int, error) {
+	opts := options.Find().SetLimit(int64(size)).SetProjection(bson.M{"_id": 1, "vehicleId": 1})
+	cursor, err := bookingCollection.Find(ctx, withoutCompany, opts)
+	if err != nil {
+		return 0, err
+	}
+	var docs []struct {
+		ID        interface{} `bson:"_id"`
+		VehicleID string      `bson:"vehicleId"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return 0, err
+	}
+	if len(docs) == 0 {
+		return 0, nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		company, err := extractCompanyName(doc.VehicleID)
+		if err != nil {
+			progress.Unresolved++
+		}
+		// Matching on the missing field keeps a concurrent run from
+		// overwriting what another already set.
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID, "company": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"company": company}}))
+	}
+	res, err := bookingCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if res != nil {
+		progress.Updated += int(res.ModifiedCount)
+	}
+	progress.Scanned += len(docs)
+	return len(docs), err
+}
+
+// migrationParam reads a positive integer query parameter; max 0 means no
+// upper bound.
+func migrationParam(c *gin.Context, name string, def, max int) (int, error) {
+	v := c.Query(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || (max > 0 && n > max) {
+		if max > 0 {
+			return 0, fmt.Errorf("%s must be between 1 and %d", name, max)
+		}
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	return n, nil
+}
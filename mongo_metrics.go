@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// --- MONGO COMMAND METRICS ---
+
+// mongoCommandMonitor times every command the driver sends, including the
+// ones handlers run on collections directly rather than through Store.
+// Started remembers each command's collection until it finishes, since the
+// finished events only carry the command name.
+func mongoCommandMonitor() *event.CommandMonitor {
+	var inFlight sync.Map
+	key := func(connectionID string, requestID int64) string {
+		return connectionID + "/" + strconv.FormatInt(requestID, 10)
+	}
+	finish := func(connectionID string, requestID int64, command string, d time.Duration, outcome string) {
+		collection := ""
+		if v, ok := inFlight.LoadAndDelete(key(connectionID, requestID)); ok {
+			collection = v.(string)
+		}
+		mongoLatency.observe(latencyKey{command, collection, outcome}, d)
+	}
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			inFlight.Store(key(e.ConnectionID, e.RequestID), commandCollection(e.CommandName, e.Command))
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finish(e.ConnectionID, e.RequestID, e.CommandName, e.Duration, "ok")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finish(e.ConnectionID, e.RequestID, e.CommandName, e.Duration, "error")
+		},
+	}
+}
+
+// commandCollection is the collection a command runs on: the value of the
+// command's own field for find, insert, aggregate and the like, or the
+// collection field of a getMore. Commands without one, such as ping, and
+// redacted ones get "".
+func commandCollection(command string, cmd bson.Raw) string {
+	field := command
+	if command == "getMore" {
+		field = "collection"
+	}
+	value, err := cmd.LookupErr(field)
+	if err != nil {
+		return ""
+	}
+	collection, _ := value.StringValueOK()
+	return collection
+}
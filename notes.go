@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Note is a dispatcher's free-text remark on a booking.
+type Note struct {
+	Text      string    `json:"note" bson:"note" xml:"note"`
+	Author    string    `json:"author,omitempty" bson:"author,omitempty" xml:"author,omitempty"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt" xml:"createdAt"`
+}
+
+// Notes are for people, so they may run longer than MAX_FIELD_LENGTH.
+const maxNoteLength = 4000
+
+// handleAddBookingNote appends a note to the booking with the confirmation
+// code (the newest, as for GET /bookings/:confirmationCode) and returns all
+// of its notes.
+func handleAddBookingNote(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Note   string `json:"note"`
+			Author string `json:"author"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: " + err.Error()})
+			return
+		}
+		note := Note{Text: strings.TrimSpace(req.Note), Author: strings.TrimSpace(req.Author), CreatedAt: time.Now().UTC()}
+		if note.Text == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "note is required"})
+			return
+		}
+		if utf8.RuneCountInString(note.Text) > maxNoteLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("note is longer than %d characters", maxNoteLength), "code": "FIELD_TOO_LONG"})
+			return
+		}
+		if err := checkFieldLengths(cfg.MaxFieldLength, []namedField{{"author", note.Author}}); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "FIELD_TOO_LONG"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		booking, err := store.AddBookingNote(ctx, c.Param("confirmationCode"), note)
+		if err == errBookingNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found", "code": "BOOKING_NOT_FOUND"})
+			return
+		}
+		if err != nil {
+			fmt.Println("❌ Adding booking note failed:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add note"})
+			return
+		}
+		if err := store.InsertLog(ctx, bookingLogEntry(cfg, booking, "NOTE_ADDED")); err != nil {
+			fmt.Println("Error saving log:", err)
+		}
+		c.JSON(http.StatusCreated, gin.H{"notes": booking.Notes})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// --- NOTIFICATIONS ---
+
+// NotificationEvent is what the service tells the outside world about a
+// booking, e.g. that its reminder is due.
+type NotificationEvent struct {
+	Event             string    `json:"event"`
+	ConfirmationCode  string    `json:"confirmationCode"`
+	VehicleID         string    `json:"vehicleId"`
+	UserID            string    `json:"userId"`
+	ServiceCenterID   string    `json:"serviceCenterId"`
+	ServiceCenterName string    `json:"serviceCenterName,omitempty"`
+	DateTime          string    `json:"dateTime"`                // UTC
+	LocalDateTime     string    `json:"localDateTime,omitempty"` // at the center, when it has a timezone
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+func bookingEvent(event string, booking DBBooking) NotificationEvent {
+	n := NotificationEvent{
+		Event:             event,
+		ConfirmationCode:  booking.ConfirmationCode,
+		VehicleID:         booking.VehicleID,
+		UserID:            booking.UserID,
+		ServiceCenterID:   booking.ScheduledService.ServiceCenterID,
+		ServiceCenterName: booking.ScheduledService.ServiceCenterName,
+		DateTime:          booking.ScheduledService.DateTime,
+		Timestamp:         time.Now().UTC(),
+	}
+	if booking.ScheduledService.Timezone != "" {
+		n.LocalDateTime = booking.ScheduledService.LocalDateTime()
+	}
+	return n
+}
+
+// Notifier delivers events. An error means the event was not delivered and
+// may be sent again.
+type Notifier interface {
+	Notify(ctx context.Context, event NotificationEvent) error
+}
+
+// newNotifier posts events to webhookURL (NOTIFY_WEBHOOK_URL), or only logs
+// them when it is empty.
+func newNotifier(webhookURL string) Notifier {
+	if webhookURL == "" {
+		return logNotifier{}
+	}
+	return webhookNotifier{url: webhookURL, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+const webhookTimeout = 5 * time.Second
+
+// webhookNotifier POSTs each event as JSON; any non-2xx answer is a failure.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n webhookNotifier) Notify(ctx context.Context, event NotificationEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// logNotifier only prints events, for deployments without NOTIFY_WEBHOOK_URL.
+type logNotifier struct{}
+
+func (logNotifier) Notify(_ context.Context, event NotificationEvent) error {
+	fmt.Printf("🔔 %s for %s (%s at %s)\n", event.Event, event.VehicleID, event.ConfirmationCode, event.DateTime)
+	return nil
+}
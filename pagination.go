@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// --- PAGINATION ---
+
+const maxPageLimit = 500
+
+// pageParams is an optional window over a list. Limit 0 means "everything",
+// which keeps the list endpoints backward compatible.
+type pageParams struct {
+	Limit  int64
+	Offset int64
+}
+
+func parsePageParams(c *gin.Context) (pageParams, error) {
+	var p pageParams
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return p, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		p.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("offset must be a non-negative integer")
+		}
+		p.Offset = n
+	}
+	return p, nil
+}
+
+func (p pageParams) apply(opts *options.FindOptions) *options.FindOptions {
+	if p.Offset > 0 {
+		opts.SetSkip(p.Offset)
+	}
+	if p.Limit > 0 {
+		opts.SetLimit(p.Limit)
+	}
+	return opts
+}
+
+// ListParams is the window and order a list endpoint was asked for with
+// ?limit=, ?offset=, ?sort= and ?order=.
+type ListParams struct {
+	Page      pageParams
+	SortField string // empty keeps storage order
+	SortAsc   bool   // ?order=asc; the default is newest first
+}
+
+// parseListParams reads and validates the list query parameters. sortFields
+// are the fields the endpoint can sort by; without any, ?sort= is rejected.
+func parseListParams(c *gin.Context, sortFields ...string) (ListParams, error) {
+	var l ListParams
+	page, err := parsePageParams(c)
+	if err != nil {
+		return l, err
+	}
+	l.Page = page
+
+	if sortField := c.Query("sort"); sortField != "" {
+		if !slices.Contains(sortFields, sortField) {
+			if len(sortFields) == 0 {
+				return l, fmt.Errorf("sort is not supported here")
+			}
+			return l, fmt.Errorf("sort must be one of: %s", strings.Join(sortFields, ", "))
+		}
+		l.SortField = sortField
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		l.SortAsc = true
+	case "desc":
+	default:
+		return l, fmt.Errorf("order must be asc or desc")
+	}
+	return l, nil
+}
+
+func (l ListParams) apply(opts *options.FindOptions) *options.FindOptions {
+	l.Page.apply(opts)
+	if l.SortField != "" {
+		direction := -1
+		if l.SortAsc {
+			direction = 1
+		}
+		opts.SetSort(bson.D{{Key: l.SortField, Value: direction}})
+	}
+	return opts
+}
+
+// setPaginationHeaders writes X-Total-Count and, for windowed requests, a
+// GitHub-style Link header with rel="next"/rel="prev".
+func setPaginationHeaders(c *gin.Context, total int64, p pageParams) {
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if p.Limit == 0 {
+		return
+	}
+
+	var links []string
+	if p.Offset+p.Limit < total {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(c, p.Offset+p.Limit, p.Limit)))
+	}
+	if p.Offset > 0 {
+		prev := p.Offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(c, prev, p.Limit)))
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
+
+// pageURL rebuilds the current request URL with a different window, keeping
+// every other query parameter.
+func pageURL(c *gin.Context, offset, limit int64) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	query := c.Request.URL.Query()
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	query.Set("limit", strconv.FormatInt(limit, 10))
+	u := url.URL{Scheme: scheme, Host: c.Request.Host, Path: c.Request.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
@@ -0,0 +1,23 @@
+package main
+
+import "strings"
+
+// Booking priority tiers from fleet contracts.
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// normalizePriority defaults an empty priority to normal and reports whether
+// the value is one of the known tiers.
+func normalizePriority(p string) (string, bool) {
+	p = strings.ToLower(strings.TrimSpace(p))
+	switch p {
+	case "":
+		return PriorityNormal, true
+	case PriorityLow, PriorityNormal, PriorityHigh:
+		return p, true
+	}
+	return p, false
+}
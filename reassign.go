@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReassignOption is a center a dispatcher could move a booking to.
+type ReassignOption struct {
+	CenterAvailability
+	CanHonorSchedule bool `json:"canHonorSchedule"`
+}
+
+// handleReassignOptions lists the active centers, other than the current one,
+// that a booking could be moved to, most free slots first.
+func handleReassignOptions(cfg Config, lookup *centerLookup) gin.HandlerFunc {
+	rules := cfg.capacityRules()
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		var booking DBBooking
+		err := bookingCollection.FindOne(ctx, bson.M{"confirmationCode": c.Param("confirmationCode")}).Decode(&booking)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking"})
+			return
+		}
+
+		// Free slots are counted around the booking's own time when slots are on.
+		scheduledAt, _ := parseFlexibleTime(booking.ScheduledService.DateTime)
+
+		centers, err := lookup.activeCenters(ctx)
+		if err != nil {
+			respondCenterLookupError(c, err)
+			return
+		}
+
+		options := []ReassignOption{}
+		for _, center := range centers {
+			if center.ID == "" || center.ID == booking.ScheduledService.ServiceCenterID {
+				continue
+			}
+			availability := rules.centerAvailability(center, scheduledAt)
+			options = append(options, ReassignOption{
+				CenterAvailability: availability,
+				CanHonorSchedule:   availability.FreeSlots > 0,
+			})
+		}
+		sort.SliceStable(options, func(a, b int) bool {
+			return options[a].FreeSlots > options[b].FreeSlots
+		})
+
+		company, _ := extractCompanyName(booking.VehicleID)
+		c.JSON(http.StatusOK, gin.H{
+			"confirmationCode": booking.ConfirmationCode,
+			"company":          company,
+			"currentCenterId":  booking.ScheduledService.ServiceCenterID,
+			"scheduledAt":      booking.ScheduledService.DateTime,
+			"options":          options,
+		})
+	}
+}
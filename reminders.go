@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// --- APPOINTMENT REMINDERS ---
+
+// runReminderScheduler periodically sends a REMINDER_DUE event for every
+// CONFIRMED booking scheduled within REMINDER_LEAD from now through notifier.
+func runReminderScheduler(cfg Config, notifier Notifier) {
+	ticker := time.NewTicker(cfg.ReminderSweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
+		sent, err := sendDueReminders(ctx, cfg, notifier, time.Now().UTC())
+		cancel()
+		if err != nil {
+			fmt.Println("⚠️ Reminder sweep failed:", err)
+		}
+		if sent > 0 {
+			fmt.Printf("🔄 Sent %d booking reminder(s)\n", sent)
+		}
+	}
+}
+
+// sendDueReminders notifies each booking due within REMINDER_LEAD of now
+// that hasn't had its reminder yet. The booking is claimed before the
+// notification goes out, so replicas and restarts never send a reminder
+// twice; a failed notification gives the claim back for the next sweep.
+func sendDueReminders(ctx context.Context, cfg Config, notifier Notifier, now time.Time) (int, error) {
+	bookings, _, err := store.FindBookings(ctx, BookingQuery{
+		Status:        StatusConfirmed,
+		ScheduledFrom: now,
+		ScheduledTo:   now.Add(cfg.ReminderLead),
+		ReminderDue:   true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, booking := range bookings {
+		if err := store.ClaimReminder(ctx, booking, now); err != nil {
+			if !errors.Is(err, errBookingNotFound) {
+				fmt.Printf("❌ Could not claim reminder for %s: %v\n", booking.ConfirmationCode, err)
+			}
+			continue
+		}
+		if err := notifier.Notify(ctx, bookingEvent("REMINDER_DUE", booking)); err != nil {
+			fmt.Printf("❌ Reminder for %s not delivered: %v\n", booking.ConfirmationCode, err)
+			if err := store.ReleaseReminder(ctx, booking); err != nil {
+				fmt.Printf("⚠️ Reminder for %s will not be retried: %v\n", booking.ConfirmationCode, err)
+			}
+			continue
+		}
+		sent++
+		if err := store.InsertLog(ctx, bookingLogEntry(cfg, booking, "REMINDER_DUE")); err != nil {
+			fmt.Println("Error saving log:", err)
+		}
+	}
+	return sent, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// --- MONGO WRITE RETRY ---
+
+const defaultWriteRetryAttempts = 3
+
+const writeRetryBaseDelay = 100 * time.Millisecond
+
+// isRetryableWriteError reports whether err is the kind of blip we see during
+// Atlas failovers (dropped connections, elections) rather than a real rejection.
+func isRetryableWriteError(err error) bool {
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+	var labeled mongo.LabeledError
+	if errors.As(err, &labeled) {
+		return labeled.HasErrorLabel("TransientTransactionError") || labeled.HasErrorLabel("RetryableWriteError")
+	}
+	return false
+}
+
+// withWriteRetry runs write up to attempts times in total (MONGO_WRITE_RETRIES),
+// retrying retryable failures with exponential backoff. Non-retryable errors
+// are returned immediately.
+func withWriteRetry(ctx context.Context, attempts int, name string, write func() error) error {
+	var err error
+	delay := writeRetryBaseDelay
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = write()
+		if err == nil || !isRetryableWriteError(err) || attempt == attempts {
+			return err
+		}
+		fmt.Printf("⚠️ %s failed (attempt %d/%d), retrying in %s: %v\n", name, attempt, attempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+	}
+	return err
+}
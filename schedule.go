@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// handleGetSchedule lists active bookings whose scheduled time falls in
+// [from, to], ordered by that time. Scheduled times are stored as normalized
+// RFC3339 UTC strings, which sort chronologically, so a string range works.
+func handleGetSchedule(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dateRange := bson.M{"$ne": ""}
+		if from := c.Query("from"); from != "" {
+			t, err := parseFlexibleTime(from)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from: " + err.Error()})
+				return
+			}
+			dateRange["$gte"] = formatTimestamp(t)
+		}
+		if to := c.Query("to"); to != "" {
+			t, err := parseFlexibleTime(to)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to: " + err.Error()})
+				return
+			}
+			dateRange["$lte"] = formatTimestamp(t)
+		}
+
+		filter := bson.M{
+			"scheduledService.dateTime": dateRange,
+			"status":                    bson.M{"$nin": inactiveStatuses},
+		}
+		if centerID := c.Query("serviceCenterId"); centerID != "" {
+			filter["scheduledService.serviceCenterId"] = centerID
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		opts := options.Find().SetSort(bson.D{{Key: "scheduledService.dateTime", Value: 1}})
+		cursor, err := bookingCollection.Find(ctx, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		bookings := []DBBooking{}
+		if err = cursor.All(ctx, &bookings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding schedule"})
+			return
+		}
+		c.JSON(http.StatusOK, bookings)
+	}
+}
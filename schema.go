@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- JSON SCHEMA ---
+
+// jsonSchemaFor describes a request struct as JSON Schema, driven by its tags:
+// `json` for names, `binding:"required"` for required fields, and the
+// optional `format` and `enum` (comma separated) tags.
+func jsonSchemaFor(t reflect.Type) gin.H {
+	if t == reflect.TypeOf(time.Time{}) {
+		return gin.H{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchemaFor(t.Elem())
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return gin.H{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return gin.H{"type": "array", "items": jsonSchemaFor(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": jsonSchemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := gin.H{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			prop := jsonSchemaFor(field.Type)
+			if format := field.Tag.Get("format"); format != "" {
+				prop["format"] = format
+			}
+			if enum := field.Tag.Get("enum"); enum != "" {
+				prop["enum"] = strings.Split(enum, ",")
+			}
+			properties[name] = prop
+
+			for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+				if rule == "required" {
+					required = append(required, name)
+				}
+			}
+		}
+		schema := gin.H{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return gin.H{}
+}
+
+var bookingRequestSchema = func() gin.H {
+	schema := jsonSchemaFor(reflect.TypeOf(IncomingBookingRequest{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "IncomingBookingRequest"
+	return schema
+}()
+
+func handleBookingSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, bookingRequestSchema)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func preflight(r *gin.Engine, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "/bookings", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "Authorization")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func simpleRequest(r *gin.Engine, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	req.Header.Set("Origin", origin)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	r := newTestRouter(t, testConfig(t))
+
+	w := simpleRequest(r, "https://app.example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	for header, want := range map[string]string{
+		"X-Content-Type-Options":       "nosniff",
+		"X-Frame-Options":              "DENY",
+		"Referrer-Policy":              "no-referrer",
+		"Content-Security-Policy":      "default-src 'none'; frame-ancestors 'none'",
+		"Cross-Origin-Resource-Policy": "cross-origin",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCORSDefaultsAllowAnyOrigin(t *testing.T) {
+	r := newTestRouter(t, testConfig(t))
+
+	w := preflight(r, "https://anywhere.example")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight status = %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("preflight Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "43200" {
+		t.Errorf("Access-Control-Max-Age = %q, want the 12h default", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none by default", got)
+	}
+
+	if got := simpleRequest(r, "https://anywhere.example").Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("simple request Access-Control-Allow-Origin = %q, want *", got)
+	}
+}
+
+func TestCORSAllowlist(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com, https://ops.example.com")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	t.Setenv("CORS_MAX_AGE", "10m")
+	r := newTestRouter(t, testConfig(t))
+
+	w := preflight(r, "https://ops.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight status = %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://ops.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("preflight %s = %q, want %q", header, got, want)
+		}
+	}
+
+	w = simpleRequest(r, "https://app.example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("simple request status = %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("simple request Access-Control-Allow-Origin = %q, want the request origin", got)
+	}
+
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"preflight":      preflight(r, "https://evil.example"),
+		"simple request": simpleRequest(r, "https://evil.example"),
+	} {
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s from an unlisted origin: status = %d, want 403", name, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%s from an unlisted origin: Access-Control-Allow-Origin = %q, want none", name, got)
+		}
+	}
+}
+
+func TestCORSMaxAgeZeroOmitsHeader(t *testing.T) {
+	t.Setenv("CORS_MAX_AGE", "0")
+	r := newTestRouter(t, testConfig(t))
+
+	w := preflight(r, "https://app.example.com")
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("Access-Control-Max-Age = %q, want none with CORS_MAX_AGE=0", got)
+	}
+}
+
+func TestBulkCancelRequiresAdminToken(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "s3cret")
+	r := newTestRouter(t, testConfig(t), testCenter("C1", 5, 0))
+	if w := serve(r, http.MethodPost, "/book-service", autoBooking); w.Code != http.StatusCreated {
+		t.Fatalf("booking: status = %d, body %s", w.Code, w.Body)
+	}
+
+	const cancelAll = `{"serviceCenterId":"C1","confirm":true}`
+	for name, auth := range map[string]string{"no token": "", "wrong token": "Bearer guess"} {
+		req := httptest.NewRequest(http.MethodPost, "/bookings/bulk-cancel", strings.NewReader(cancelAll))
+		req.Header.Set("Content-Type", "application/json")
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", name, w.Code)
+		}
+	}
+
+	var bookings []DBBooking
+	decode(t, serve(r, http.MethodGet, "/bookings", ""), &bookings)
+	if len(bookings) != 1 || bookings[0].Status != StatusConfirmed {
+		t.Fatalf("bookings = %+v, want the booking still confirmed", bookings)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/bookings/bulk-cancel", strings.NewReader(cancelAll))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("with the admin token: status = %d, body %s", w.Code, w.Body)
+	}
+}
+
+// CORS answers preflights and rejected origins without running later
+// middleware, so the security headers must already be set by then.
+func TestSecurityHeadersOnCORSResponses(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com")
+	r := newTestRouter(t, testConfig(t))
+
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"preflight":                     preflight(r, "https://app.example.com"),
+		"preflight from another origin": preflight(r, "https://evil.example"),
+		"request from another origin":   simpleRequest(r, "https://evil.example"),
+	} {
+		for _, header := range []string{"X-Content-Type-Options", "X-Frame-Options", "Content-Security-Policy"} {
+			if w.Header().Get(header) == "" {
+				t.Errorf("%s (status %d): %s missing", name, w.Code, header)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// centerContract is the shape we rely on in 'auto_ai_db.service_centers':
+// each field and the BSON types we know how to read.
+var centerContract = []struct {
+	field string
+	types []bsontype.Type
+}{
+	{"centerId", []bsontype.Type{bsontype.String}},
+	{"name", []bsontype.Type{bsontype.String}},
+	{"capacity", []bsontype.Type{bsontype.Int32, bsontype.Int64}},
+	{"is_active", []bsontype.Type{bsontype.Boolean}},
+	{"bookings", []bsontype.Type{bsontype.Array}},
+}
+
+// runStartupSelfTest reads a few center documents as raw BSON and warns if
+// they no longer match centerContract or fail to decode, so drift on the admin
+// side shows up at boot rather than as failed bookings. It never stops startup.
+func runStartupSelfTest(cfg Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
+	defer cancel()
+
+	filter := bson.M{}
+	if cfg.SelfTestCenterID != "" {
+		filter["centerId"] = cfg.SelfTestCenterID
+	}
+	cursor, err := serviceCenterCollection.Find(ctx, filter, options.Find().SetLimit(5))
+	if err != nil {
+		fmt.Println("⚠️ Self-test: could not query service centers:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	checked := 0
+	problems := []string{}
+	for cursor.Next(ctx) {
+		checked++
+		doc := cursor.Current
+		id, ok := doc.Lookup("centerId").StringValueOK()
+		if !ok {
+			id = fmt.Sprintf("center #%d", checked)
+		}
+		for _, want := range centerContract {
+			value, err := doc.LookupErr(want.field)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: missing %q", id, want.field))
+				continue
+			}
+			if !typeAllowed(value.Type, want.types) {
+				problems = append(problems, fmt.Sprintf("%s: %q is %s", id, want.field, value.Type))
+			}
+		}
+		var center ServiceCenterDBModel
+		if err := bson.Unmarshal(doc, &center); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: does not decode: %v", id, err))
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		fmt.Println("⚠️ Self-test: reading service centers failed:", err)
+		return
+	}
+
+	switch {
+	case checked == 0:
+		fmt.Println("⚠️ Self-test: no service centers found to check")
+	case len(problems) > 0:
+		fmt.Printf("⚠️ Self-test: service center documents may have changed shape:\n  - %s\n", strings.Join(problems, "\n  - "))
+	default:
+		fmt.Printf("Self-test: %d service center document(s) match the expected shape\n", checked)
+	}
+}
+
+func typeAllowed(t bsontype.Type, allowed []bsontype.Type) bool {
+	for _, a := range allowed {
+		if t == a {
+			return true
+		}
+	}
+	return false
+}
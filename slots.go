@@ -0,0 +1,155 @@
+package main
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// --- TIME SLOTS ---
+
+// capacityRules is how a center's capacity is counted, from the Config.
+type capacityRules struct {
+	// slotDuration is how long one booking occupies a center (SLOT_DURATION).
+	// Zero keeps the old behaviour where capacity is a lifetime total and
+	// every booking on the center counts against it.
+	slotDuration time.Duration
+	// missingCapacityUnlimited gives centers without a 'capacity' field
+	// unlimited room instead of none (MISSING_CAPACITY).
+	missingCapacityUnlimited bool
+}
+
+func (cfg Config) capacityRules() capacityRules {
+	return capacityRules{
+		slotDuration:             cfg.SlotDuration,
+		missingCapacityUnlimited: cfg.MissingCapacity == MissingCapacityUnlimited,
+	}
+}
+
+// slotWindow returns the open interval of start times whose slot overlaps a
+// slot starting at `at`. ok is false when bookings are not time-sliced or
+// there is no time to slice by.
+func (rules capacityRules) slotWindow(at time.Time) (from, to time.Time, ok bool) {
+	if rules.slotDuration <= 0 || at.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	return at.Add(-rules.slotDuration), at.Add(rules.slotDuration), true
+}
+
+// bookedSlotsAt counts the center's bookings that overlap a slot starting at
+// `at`, or all of them when bookings are not time-sliced.
+func (rules capacityRules) bookedSlotsAt(center ServiceCenterDBModel, at time.Time) int {
+	from, to, ok := rules.slotWindow(at)
+	if !ok {
+		return len(center.Bookings)
+	}
+	n := 0
+	for _, item := range center.Bookings {
+		// Entries without a usable time can't be placed in a slot, so they
+		// conservatively block every slot.
+		scheduled, found := centerBookingTime(item)
+		if !found || (scheduled.After(from) && scheduled.Before(to)) {
+			n++
+		}
+	}
+	return n
+}
+
+// centerBookingTime digs scheduledService.dateTime out of an entry of a
+// center's 'bookings' array. Legacy entries may hold any format
+// parseFlexibleTime reads, so the raw strings can't be compared.
+func centerBookingTime(item interface{}) (time.Time, bool) {
+	service, ok := documentField(item, "scheduledService")
+	if !ok {
+		return time.Time{}, false
+	}
+	value, ok := documentField(service, "dateTime")
+	if !ok {
+		return time.Time{}, false
+	}
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return time.Time{}, false
+	}
+	t, err := parseFlexibleTime(s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// documentField reads key from a booking entry as the Mongo driver (bson
+// documents) or the CENTERS_FILE provider (plain JSON maps) decodes it.
+func documentField(doc interface{}, key string) (interface{}, bool) {
+	switch d := doc.(type) {
+	case primitive.D:
+		for _, e := range d {
+			if e.Key == key {
+				return e.Value, true
+			}
+		}
+	case primitive.M:
+		v, ok := d[key]
+		return v, ok
+	case map[string]interface{}:
+		v, ok := d[key]
+		return v, ok
+	}
+	return nil, false
+}
+
+// bookedSlotsExpr is the aggregation counterpart of bookedSlotsAt, used in
+// the conditional slot reservation.
+func (rules capacityRules) bookedSlotsExpr(at time.Time) bson.M {
+	bookings := bson.M{"$cond": bson.A{bson.M{"$isArray": "$bookings"}, "$bookings", bson.A{}}}
+	from, to, ok := rules.slotWindow(at)
+	if !ok {
+		return bson.M{"$size": bookings}
+	}
+	return bson.M{"$size": bson.M{"$filter": bson.M{
+		"input": bookings,
+		"cond": bson.M{"$let": bson.M{
+			"vars": bson.M{"scheduled": flexibleTimeExpr("$$this.scheduledService.dateTime")},
+			"in": bson.M{"$or": bson.A{
+				bson.M{"$eq": bson.A{"$$scheduled", nil}},
+				bson.M{"$and": bson.A{
+					bson.M{"$gt": bson.A{"$$scheduled", from}},
+					bson.M{"$lt": bson.A{"$$scheduled", to}},
+				}},
+			}},
+		}},
+	}}}
+}
+
+// Patterns of the strings parseFlexibleTime reads, for flexibleTimeExpr.
+// $dateFromString alone is more lenient, e.g. it takes a bare date.
+const (
+	epochSecondsPattern = `^[0-9]{1,10}$`
+	epochMillisPattern  = `^[0-9]{11,}$`
+	rfc3339Pattern      = `^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})?$`
+)
+
+// flexibleTimeExpr is the aggregation counterpart of parseFlexibleTime: the
+// date in the string at field, or null where parseFlexibleTime would fail.
+func flexibleTimeExpr(field string) bson.M {
+	value := bson.M{"$trim": bson.M{"input": field}}
+	matches := func(pattern string) bson.M {
+		return bson.M{"$regexMatch": bson.M{"input": value, "regex": pattern}}
+	}
+	// $toLong and $toDate would fail the whole update on an out-of-range
+	// epoch; parseFlexibleTime rejects those, so they become null.
+	toLong := bson.M{"$convert": bson.M{"input": value, "to": "long", "onError": nil, "onNull": nil}}
+	epochMillis := func(millis interface{}) bson.M {
+		return bson.M{"$convert": bson.M{"input": millis, "to": "date", "onError": nil, "onNull": nil}}
+	}
+	return bson.M{"$switch": bson.M{
+		"branches": bson.A{
+			bson.M{"case": bson.M{"$ne": bson.A{bson.M{"$type": field}, "string"}}, "then": nil},
+			bson.M{"case": matches(epochSecondsPattern), "then": epochMillis(bson.M{"$multiply": bson.A{toLong, 1000}})},
+			bson.M{"case": matches(epochMillisPattern), "then": epochMillis(toLong)},
+			bson.M{"case": matches(rfc3339Pattern), "then": bson.M{"$dateFromString": bson.M{"dateString": value, "onError": nil, "onNull": nil}}},
+		},
+		"default": nil,
+	}}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sony/gobreaker"
+)
+
+// How long /system-status waits for MongoDB to answer a ping.
+const statusPingTimeout = 2 * time.Second
+
+// handleSystemStatus reports "Active", or "Degraded" when MongoDB doesn't
+// answer a ping or the center circuit breaker is open. Every evaluation is
+// kept for /system-status/history.
+func handleSystemStatus(cfg Config, lookup *centerLookup, history *healthRing) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sample := HealthSample{
+			Timestamp:  time.Now().UTC(),
+			DatabaseOK: true,
+			UpstreamOK: lookup.breaker.State() != gobreaker.StateOpen,
+			Status:     "Active",
+		}
+		if client != nil {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), statusPingTimeout)
+			sample.DatabaseOK = client.Ping(ctx, nil) == nil
+			cancel()
+		}
+		if !sample.DatabaseOK || !sample.UpstreamOK {
+			sample.Status = "Degraded"
+		}
+		history.record(sample)
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":       sample.Status,
+			"database":     sample.DatabaseOK,
+			"upstream":     sample.UpstreamOK,
+			"centerLookup": centerLookupStats.snapshot(cfg.ColdLookupThreshold),
+			"centerCircuit": gin.H{
+				"state":  lookup.breaker.State().String(),
+				"counts": lookup.breaker.Counts(),
+			},
+		})
+	}
+}
+
+// handleHealthHistory lists the recent /system-status evaluations, oldest first.
+func handleHealthHistory(history *healthRing) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, history.list())
+	}
+}
+
+// warmUpCenterLookup pays the first-query cost at boot so the first real
+// booking doesn't.
+func warmUpCenterLookup(cfg Config, lookup *centerLookup) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := lookup.activeCenters(ctx); err != nil {
+		fmt.Println("⚠️ Center lookup warm-up failed:", err)
+		return
+	}
+	fmt.Printf("Center lookup warmed up in %s\n", time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Booking statuses that no longer hold a center slot.
+const (
+	StatusCancelled = "CANCELLED"
+	StatusCompleted = "COMPLETED"
+	StatusExpired   = "EXPIRED" // PENDING for longer than PENDING_TTL
+)
+
+// Statuses of a booking that still holds its slot. A booking sent without a
+// status is stored as StatusConfirmed.
+const (
+	StatusPending     = "PENDING" // not confirmed by the client yet
+	StatusConfirmed   = "CONFIRMED"
+	StatusSyncPending = "SYNC_PENDING" // saved, but its required push failed; no center capacity held
+)
+
+// normalizeBookingStatus maps a client-supplied status, in any case and with
+// stray spaces, to one a booking may be created with. The rest are set by the
+// service as a booking moves along.
+func normalizeBookingStatus(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return StatusConfirmed, true
+	case StatusPending, StatusConfirmed:
+		return s, true
+	}
+	return s, false
+}
+
+var inactiveStatuses = []string{StatusCancelled, StatusCompleted, StatusExpired}
+
+// activeVehicleFilter matches the vehicle's booking that still holds a slot.
+func activeVehicleFilter(vehicleID string) bson.M {
+	return bson.M{"vehicleId": vehicleID, "status": bson.M{"$nin": inactiveStatuses}}
+}
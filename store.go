@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- BOOKING & LOG STORE ---
+
+// Backends for bookings and logs, set from STORE.
+const (
+	StoreMongo  = "mongo"
+	StoreMemory = "memory" // demo/offline mode: nothing survives a restart
+)
+
+var errBookingNotFound = errors.New("booking not found")
+
+// BookingQuery selects bookings for the list endpoints. Zero values mean "no
+// condition".
+type BookingQuery struct {
+	VehicleID     string
+	UserID        string
+	Action        string // the booking's action, e.g. EMERGENCY
+	Anonymous     bool   // userId missing, empty or AnonymousUserID
+	Status        string
+	CodePrefix    string            // confirmationCode starts with this
+	ActiveOnly    bool              // leave out cancelled, completed and expired bookings
+	CenterID      string            // scheduledService.serviceCenterId
+	Unassigned    bool              // center missing, empty or the literal "null"
+	Metadata      map[string]string // exact matches on metadata keys
+	CreatedBefore time.Time         // zero means no bound
+	// ScheduledFrom and ScheduledTo bound scheduledService.dateTime to
+	// [From, To); zero means open.
+	ScheduledFrom, ScheduledTo time.Time
+	ReminderDue                bool       // no reminder sent yet
+	List                       ListParams // sort by "createdAt", "updatedAt" or "scheduledService.dateTime"
+}
+
+// LogQuery selects log entries; Action and ActionPrefix are exclusive.
+type LogQuery struct {
+	Action       string
+	ActionPrefix string
+	From, To     time.Time  // timestamp range [From, To); zero means open
+	List         ListParams // sort by "timestamp"
+}
+
+// Store is where bookings and their audit logs live. Handlers that only need
+// these operations work on every backend; the rest still talk to MongoDB
+// directly and are switched off by requireMongoStore in memory mode.
+type Store interface {
+	// FindActiveBooking returns the vehicle's booking that still holds a
+	// slot, or errBookingNotFound.
+	FindActiveBooking(ctx context.Context, vehicleID string) (DBBooking, error)
+	// FindBookingByCode returns the newest booking with the code, or
+	// errBookingNotFound.
+	FindBookingByCode(ctx context.Context, confirmationCode string) (DBBooking, error)
+	// FindBookings returns one page of matching bookings and the total count.
+	FindBookings(ctx context.Context, q BookingQuery) ([]DBBooking, int64, error)
+	CountBookings(ctx context.Context, q BookingQuery) (int64, error)
+	// EachBooking calls fn for every booking on the page in order, as it is
+	// read, stopping at the first error.
+	EachBooking(ctx context.Context, q BookingQuery, fn func(DBBooking) error) error
+	// SaveBookingWithLog inserts the booking, or updates the vehicle's active
+	// booking when isUpdate is set, together with its audit entry. changes are
+	// appended to the booking's History. A booking without a user is stored
+	// with AnonymousUserID.
+	SaveBookingWithLog(ctx context.Context, booking DBBooking, isUpdate bool, changes []ChangeEvent, entry LogEntry) (logPersisted bool, err error)
+	// SetBookingStatus moves the booking to status together with its audit
+	// entry and a History event for actor, but only while it still has
+	// booking.Status. It returns errBookingNotFound when the booking has moved
+	// on in the meantime.
+	SetBookingStatus(ctx context.Context, booking DBBooking, status, actor string, entry LogEntry) error
+
+	// AddBookingNote appends note to the newest booking with the code and
+	// returns the booking as updated, or errBookingNotFound.
+	AddBookingNote(ctx context.Context, confirmationCode string, note Note) (DBBooking, error)
+	// ClaimReminder marks the booking's reminder as sent at, unless it
+	// already is, in which case it returns errBookingNotFound.
+	ClaimReminder(ctx context.Context, booking DBBooking, at time.Time) error
+	// ReleaseReminder undoes ClaimReminder after a failed notification.
+	ReleaseReminder(ctx context.Context, booking DBBooking) error
+
+	InsertLog(ctx context.Context, entry LogEntry) error
+	CountLogs(ctx context.Context, q LogQuery) (int64, error)
+	// EachLog calls fn for every matching entry in order, stopping at the
+	// first error.
+	EachLog(ctx context.Context, q LogQuery, fn func(LogEntry) error) error
+
+	// SaveDeadLetter inserts the dead letter or replaces the one with its ID.
+	SaveDeadLetter(ctx context.Context, dl DeadLetter) error
+	// FindDeadLetter returns the dead letter, or errDeadLetterNotFound.
+	FindDeadLetter(ctx context.Context, id string) (DeadLetter, error)
+	// ClaimDeadLetterReplay marks the dead letter as being replayed at and
+	// returns it, unless it was already replayed successfully or another
+	// replay holds it, in which case it returns errDeadLetterNotFound.
+	ClaimDeadLetterReplay(ctx context.Context, id string, at time.Time) (DeadLetter, error)
+	FindDeadLetters(ctx context.Context, list ListParams) ([]DeadLetter, int64, error)
+}
+
+func withUserID(booking DBBooking) DBBooking {
+	if booking.UserID == "" {
+		booking.UserID = AnonymousUserID
+	}
+	return booking
+}
+
+// store is the backend chosen in main.
+var store Store = mongoStore{historyLimit: defaultBookingHistoryLimit, writeRetries: defaultWriteRetryAttempts}
+
+// requireMongoStore guards the endpoints that still query MongoDB directly.
+func requireMongoStore(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.Store != StoreMongo {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Not available with STORE=" + cfg.Store, "code": "STORE_UNSUPPORTED"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStore keeps bookings and logs in process memory for demos and
+// offline work. Writes are atomic under one lock, like a transaction.
+type memoryStore struct {
+	mu          sync.RWMutex
+	bookings    []DBBooking
+	logs        []LogEntry
+	deadLetters []DeadLetter
+
+	historyLimit int // BOOKING_HISTORY_LIMIT
+}
+
+func newMemoryStore(historyLimit int) *memoryStore {
+	return &memoryStore{historyLimit: historyLimit}
+}
+
+func isActiveStatus(status string) bool {
+	for _, inactive := range inactiveStatuses {
+		if status == inactive {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *memoryStore) activeIndex(vehicleID string) int {
+	for i, b := range m.bookings {
+		if b.VehicleID == vehicleID && isActiveStatus(b.Status) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *memoryStore) FindActiveBooking(_ context.Context, vehicleID string) (DBBooking, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if i := m.activeIndex(vehicleID); i >= 0 {
+		return m.bookings[i], nil
+	}
+	return DBBooking{}, errBookingNotFound
+}
+
+func (m *memoryStore) FindBookingByCode(_ context.Context, confirmationCode string) (DBBooking, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	found := m.newestIndex(confirmationCode)
+	if found < 0 {
+		return DBBooking{}, errBookingNotFound
+	}
+	return m.bookings[found], nil
+}
+
+func (m *memoryStore) newestIndex(confirmationCode string) int {
+	found := -1
+	for i, b := range m.bookings {
+		if b.ConfirmationCode == confirmationCode && (found < 0 || b.CreatedAt.After(m.bookings[found].CreatedAt)) {
+			found = i
+		}
+	}
+	return found
+}
+
+func (m *memoryStore) AddBookingNote(_ context.Context, confirmationCode string, note Note) (DBBooking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := m.newestIndex(confirmationCode)
+	if found < 0 {
+		return DBBooking{}, errBookingNotFound
+	}
+	m.bookings[found].Notes = append(m.bookings[found].Notes, note)
+	return m.bookings[found], nil
+}
+
+func (q BookingQuery) matches(b DBBooking) bool {
+	if q.VehicleID != "" && b.VehicleID != q.VehicleID {
+		return false
+	}
+	if q.UserID != "" && b.UserID != q.UserID {
+		return false
+	}
+	if q.Action != "" && b.Action != q.Action {
+		return false
+	}
+	if q.Anonymous && b.UserID != "" && b.UserID != AnonymousUserID {
+		return false
+	}
+	if q.Status != "" && b.Status != q.Status {
+		return false
+	}
+	if q.ActiveOnly && !isActiveStatus(b.Status) {
+		return false
+	}
+	if q.CodePrefix != "" && !strings.HasPrefix(b.ConfirmationCode, q.CodePrefix) {
+		return false
+	}
+	if q.CenterID != "" && b.ScheduledService.ServiceCenterID != q.CenterID {
+		return false
+	}
+	if q.Unassigned {
+		if id := b.ScheduledService.ServiceCenterID; id != "" && id != "null" {
+			return false
+		}
+	}
+	for key, value := range q.Metadata {
+		if b.Metadata[key] != value {
+			return false
+		}
+	}
+	if !q.CreatedBefore.IsZero() && !b.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+	if !q.ScheduledFrom.IsZero() && b.ScheduledService.DateTime < formatTimestamp(q.ScheduledFrom) {
+		return false
+	}
+	if !q.ScheduledTo.IsZero() && b.ScheduledService.DateTime >= formatTimestamp(q.ScheduledTo) {
+		return false
+	}
+	if q.ReminderDue && b.ReminderSentAt != nil {
+		return false
+	}
+	return true
+}
+
+func (m *memoryStore) FindBookings(_ context.Context, q BookingQuery) ([]DBBooking, int64, error) {
+	m.mu.RLock()
+	matched := []DBBooking{}
+	for _, b := range m.bookings {
+		if q.matches(b) {
+			matched = append(matched, b)
+		}
+	}
+	m.mu.RUnlock()
+
+	if q.List.SortField != "" {
+		// Every sortable field orders the same as its text (RFC3339 for times).
+		key := func(b DBBooking) string {
+			switch q.List.SortField {
+			case "confirmationCode":
+				return b.ConfirmationCode
+			case "updatedAt":
+				return b.UpdatedAt.UTC().Format(time.RFC3339Nano)
+			case "scheduledService.dateTime":
+				return b.ScheduledService.DateTime
+			}
+			return b.CreatedAt.UTC().Format(time.RFC3339Nano)
+		}
+		sort.SliceStable(matched, func(i, j int) bool {
+			if q.List.SortAsc {
+				return key(matched[i]) < key(matched[j])
+			}
+			return key(matched[j]) < key(matched[i])
+		})
+	}
+	return paginate(matched, q.List.Page), int64(len(matched)), nil
+}
+
+func (m *memoryStore) CountBookings(ctx context.Context, q BookingQuery) (int64, error) {
+	_, total, err := m.FindBookings(ctx, q)
+	return total, err
+}
+
+// EachBooking walks a snapshot of the page, so fn may call back into the store.
+func (m *memoryStore) EachBooking(ctx context.Context, q BookingQuery, fn func(DBBooking) error) error {
+	bookings, _, err := m.FindBookings(ctx, q)
+	if err != nil {
+		return err
+	}
+	for _, b := range bookings {
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) SaveBookingWithLog(_ context.Context, booking DBBooking, isUpdate bool, changes []ChangeEvent, entry LogEntry) (bool, error) {
+	booking = withUserID(booking)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i := m.activeIndex(booking.VehicleID); isUpdate && i >= 0 {
+		// Like the Mongo $set, an update leaves createdAt, notes and the
+		// reminder state alone.
+		booking.CreatedAt = m.bookings[i].CreatedAt
+		booking.Notes = m.bookings[i].Notes
+		booking.ReminderSentAt = m.bookings[i].ReminderSentAt
+		booking.History = appendHistory(m.bookings[i].History, changes, m.historyLimit)
+		m.bookings[i] = booking
+	} else {
+		booking.History = appendHistory(nil, changes, m.historyLimit)
+		m.bookings = append(m.bookings, booking)
+	}
+	m.logs = append(m.logs, entry)
+	return true, nil
+}
+
+func (m *memoryStore) SetBookingStatus(_ context.Context, booking DBBooking, status, actor string, entry LogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, b := range m.bookings {
+		if b.VehicleID == booking.VehicleID && b.ConfirmationCode == booking.ConfirmationCode && b.Status == booking.Status {
+			m.bookings[i].History = appendHistory(b.History, []ChangeEvent{statusChange(b, status, actor)}, m.historyLimit)
+			m.bookings[i].Status = status
+			m.bookings[i].UpdatedAt = time.Now().UTC()
+			m.logs = append(m.logs, entry)
+			return nil
+		}
+	}
+	return errBookingNotFound
+}
+
+func (m *memoryStore) ClaimReminder(_ context.Context, booking DBBooking, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, b := range m.bookings {
+		if b.VehicleID == booking.VehicleID && b.ConfirmationCode == booking.ConfirmationCode && b.CreatedAt.Equal(booking.CreatedAt) && b.ReminderSentAt == nil {
+			m.bookings[i].ReminderSentAt = &at
+			return nil
+		}
+	}
+	return errBookingNotFound
+}
+
+func (m *memoryStore) ReleaseReminder(_ context.Context, booking DBBooking) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, b := range m.bookings {
+		if b.VehicleID == booking.VehicleID && b.ConfirmationCode == booking.ConfirmationCode && b.CreatedAt.Equal(booking.CreatedAt) {
+			m.bookings[i].ReminderSentAt = nil
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) InsertLog(_ context.Context, entry LogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logs = append(m.logs, entry)
+	return nil
+}
+
+func (q LogQuery) matches(entry LogEntry) bool {
+	if q.Action != "" && entry.Data.Action != q.Action {
+		return false
+	}
+	if !q.From.IsZero() && entry.Timestamp < formatTimestamp(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && entry.Timestamp >= formatTimestamp(q.To) {
+		return false
+	}
+	return strings.HasPrefix(entry.Data.Action, q.ActionPrefix)
+}
+
+func (m *memoryStore) matchingLogs(q LogQuery) []LogEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matched := []LogEntry{}
+	for _, entry := range m.logs {
+		if q.matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+func (m *memoryStore) CountLogs(_ context.Context, q LogQuery) (int64, error) {
+	return int64(len(m.matchingLogs(q))), nil
+}
+
+func (m *memoryStore) EachLog(_ context.Context, q LogQuery, fn func(LogEntry) error) error {
+	logs := m.matchingLogs(q)
+	if q.List.SortField == "timestamp" {
+		// Timestamps are RFC3339 UTC, which sorts correctly as text.
+		sort.SliceStable(logs, func(i, j int) bool {
+			if q.List.SortAsc {
+				return logs[i].Timestamp < logs[j].Timestamp
+			}
+			return logs[j].Timestamp < logs[i].Timestamp
+		})
+	}
+	for _, entry := range paginate(logs, q.List.Page) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) SaveDeadLetter(_ context.Context, dl DeadLetter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.deadLetters {
+		if m.deadLetters[i].ID == dl.ID {
+			m.deadLetters[i] = dl
+			return nil
+		}
+	}
+	m.deadLetters = append(m.deadLetters, dl)
+	return nil
+}
+
+func (m *memoryStore) FindDeadLetter(_ context.Context, id string) (DeadLetter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, dl := range m.deadLetters {
+		if dl.ID == id {
+			return dl, nil
+		}
+	}
+	return DeadLetter{}, errDeadLetterNotFound
+}
+
+func (m *memoryStore) ClaimDeadLetterReplay(_ context.Context, id string, at time.Time) (DeadLetter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, dl := range m.deadLetters {
+		if dl.ID == id && (dl.ReplayedAt == nil || dl.ReplayStatus >= replayFailedStatus) {
+			m.deadLetters[i].ReplayedAt = &at
+			m.deadLetters[i].ReplayStatus = 0
+			return m.deadLetters[i], nil
+		}
+	}
+	return DeadLetter{}, errDeadLetterNotFound
+}
+
+func (m *memoryStore) FindDeadLetters(_ context.Context, list ListParams) ([]DeadLetter, int64, error) {
+	m.mu.RLock()
+	deadLetters := slices.Clone(m.deadLetters)
+	m.mu.RUnlock()
+
+	if list.SortField == "createdAt" {
+		sort.SliceStable(deadLetters, func(i, j int) bool {
+			if list.SortAsc {
+				return deadLetters[i].CreatedAt.Before(deadLetters[j].CreatedAt)
+			}
+			return deadLetters[j].CreatedAt.Before(deadLetters[i].CreatedAt)
+		})
+	}
+	return paginate(deadLetters, list.Page), int64(len(deadLetters)), nil
+}
+
+// paginate applies a page window to an in-memory list.
+func paginate[T any](items []T, p pageParams) []T {
+	if p.Offset >= int64(len(items)) {
+		return items[:0]
+	}
+	items = items[p.Offset:]
+	if p.Limit > 0 && p.Limit < int64(len(items)) {
+		items = items[:p.Limit]
+	}
+	return items
+}
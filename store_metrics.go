@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- STORE LATENCY METRICS ---
+
+// storeLatencyBuckets are the histogram's upper bounds in seconds.
+var storeLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// latencySeries is one label set's histogram. counts[i] holds observations
+// up to storeLatencyBuckets[i] that didn't fit a smaller bucket; the last
+// slot is everything slower.
+type latencySeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// latencyKey holds one series' label values, in the order of the
+// histogram's labels.
+type latencyKey [3]string
+
+// latencyHistogram keeps durations per label set since startup, so GET
+// /metrics can show whether a slow booking is spent in the database or
+// upstream.
+type latencyHistogram struct {
+	name, help string
+	labels     [3]string
+
+	mu     sync.Mutex
+	series map[latencyKey]*latencySeries
+}
+
+func newLatencyHistogram(name, help string, labels [3]string) *latencyHistogram {
+	return &latencyHistogram{name: name, help: help, labels: labels, series: map[latencyKey]*latencySeries{}}
+}
+
+var (
+	storeLatency = newLatencyHistogram("store_operation_duration_seconds",
+		"Duration of Store calls by backend, operation and collection.",
+		[3]string{"backend", "operation", "collection"})
+	mongoLatency = newLatencyHistogram("mongo_command_duration_seconds",
+		"Duration of MongoDB commands by command, collection and outcome.",
+		[3]string{"command", "collection", "outcome"})
+)
+
+func (h *latencyHistogram) observe(key latencyKey, d time.Duration) {
+	seconds := d.Seconds()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &latencySeries{counts: make([]uint64, len(storeLatencyBuckets)+1)}
+		h.series[key] = s
+	}
+	s.counts[sort.SearchFloat64s(storeLatencyBuckets, seconds)]++
+	s.sum += seconds
+	s.count++
+}
+
+// writePrometheus renders the histogram in the Prometheus text format.
+func (h *latencyHistogram) writePrometheus(b *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make([]latencyKey, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return slices.Compare(keys[i][:], keys[j][:]) < 0 })
+
+	name := h.name
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, h.help, name)
+	for _, key := range keys {
+		s := h.series[key]
+		pairs := make([]string, len(key))
+		for i, value := range key {
+			pairs[i] = fmt.Sprintf("%s=%q", h.labels[i], value)
+		}
+		labels := strings.Join(pairs, ",")
+		var cumulative uint64
+		for i, count := range s.counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(storeLatencyBuckets) {
+				le = fmt.Sprint(storeLatencyBuckets[i])
+			}
+			fmt.Fprintf(b, "%s_bucket{%s,le=%q} %d\n", name, labels, le, cumulative)
+		}
+		fmt.Fprintf(b, "%s_sum{%s} %g\n", name, labels, s.sum)
+		fmt.Fprintf(b, "%s_count{%s} %d\n", name, labels, s.count)
+	}
+}
+
+// handleMetrics serves the metrics in the Prometheus text format.
+func handleMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var b strings.Builder
+		storeLatency.writePrometheus(&b)
+		mongoLatency.writePrometheus(&b)
+		c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+	}
+}
+
+// Collection labels for store operations. Writes that save a booking and its
+// audit entry together touch both collections.
+const (
+	metricBookings    = "Bookings"
+	metricLogs        = "Logs"
+	metricBookingLogs = "Bookings+Logs"
+	metricDeadLetters = "DeadLetters"
+)
+
+// timedStore records how long every call into the wrapped Store takes, so
+// each backend is measured the same way. Each* calls are timed until the
+// last callback returns, which includes the caller's work per item.
+type timedStore struct {
+	store   Store
+	backend string
+}
+
+// timer starts timing one call; the returned func records it.
+func (s timedStore) timer(operation, collection string) func() {
+	start := time.Now()
+	return func() {
+		storeLatency.observe(latencyKey{s.backend, operation, collection}, time.Since(start))
+	}
+}
+
+func (s timedStore) FindActiveBooking(ctx context.Context, vehicleID string) (DBBooking, error) {
+	defer s.timer("FindActiveBooking", metricBookings)()
+	return s.store.FindActiveBooking(ctx, vehicleID)
+}
+
+func (s timedStore) FindBookingByCode(ctx context.Context, confirmationCode string) (DBBooking, error) {
+	defer s.timer("FindBookingByCode", metricBookings)()
+	return s.store.FindBookingByCode(ctx, confirmationCode)
+}
+
+func (s timedStore) FindBookings(ctx context.Context, q BookingQuery) ([]DBBooking, int64, error) {
+	defer s.timer("FindBookings", metricBookings)()
+	return s.store.FindBookings(ctx, q)
+}
+
+func (s timedStore) CountBookings(ctx context.Context, q BookingQuery) (int64, error) {
+	defer s.timer("CountBookings", metricBookings)()
+	return s.store.CountBookings(ctx, q)
+}
+
+func (s timedStore) EachBooking(ctx context.Context, q BookingQuery, fn func(DBBooking) error) error {
+	defer s.timer("EachBooking", metricBookings)()
+	return s.store.EachBooking(ctx, q, fn)
+}
+
+func (s timedStore) SaveBookingWithLog(ctx context.Context, booking DBBooking, isUpdate bool, changes []ChangeEvent, entry LogEntry) (bool, error) {
+	defer s.timer("SaveBookingWithLog", metricBookingLogs)()
+	return s.store.SaveBookingWithLog(ctx, booking, isUpdate, changes, entry)
+}
+
+func (s timedStore) SetBookingStatus(ctx context.Context, booking DBBooking, status, actor string, entry LogEntry) error {
+	defer s.timer("SetBookingStatus", metricBookingLogs)()
+	return s.store.SetBookingStatus(ctx, booking, status, actor, entry)
+}
+
+func (s timedStore) AddBookingNote(ctx context.Context, confirmationCode string, note Note) (DBBooking, error) {
+	defer s.timer("AddBookingNote", metricBookings)()
+	return s.store.AddBookingNote(ctx, confirmationCode, note)
+}
+
+func (s timedStore) ClaimReminder(ctx context.Context, booking DBBooking, at time.Time) error {
+	defer s.timer("ClaimReminder", metricBookings)()
+	return s.store.ClaimReminder(ctx, booking, at)
+}
+
+func (s timedStore) ReleaseReminder(ctx context.Context, booking DBBooking) error {
+	defer s.timer("ReleaseReminder", metricBookings)()
+	return s.store.ReleaseReminder(ctx, booking)
+}
+
+func (s timedStore) InsertLog(ctx context.Context, entry LogEntry) error {
+	defer s.timer("InsertLog", metricLogs)()
+	return s.store.InsertLog(ctx, entry)
+}
+
+func (s timedStore) CountLogs(ctx context.Context, q LogQuery) (int64, error) {
+	defer s.timer("CountLogs", metricLogs)()
+	return s.store.CountLogs(ctx, q)
+}
+
+func (s timedStore) EachLog(ctx context.Context, q LogQuery, fn func(LogEntry) error) error {
+	defer s.timer("EachLog", metricLogs)()
+	return s.store.EachLog(ctx, q, fn)
+}
+
+func (s timedStore) SaveDeadLetter(ctx context.Context, dl DeadLetter) error {
+	defer s.timer("SaveDeadLetter", metricDeadLetters)()
+	return s.store.SaveDeadLetter(ctx, dl)
+}
+
+func (s timedStore) FindDeadLetter(ctx context.Context, id string) (DeadLetter, error) {
+	defer s.timer("FindDeadLetter", metricDeadLetters)()
+	return s.store.FindDeadLetter(ctx, id)
+}
+
+func (s timedStore) ClaimDeadLetterReplay(ctx context.Context, id string, at time.Time) (DeadLetter, error) {
+	defer s.timer("ClaimDeadLetterReplay", metricDeadLetters)()
+	return s.store.ClaimDeadLetterReplay(ctx, id, at)
+}
+
+func (s timedStore) FindDeadLetters(ctx context.Context, list ListParams) ([]DeadLetter, int64, error) {
+	defer s.timer("FindDeadLetters", metricDeadLetters)()
+	return s.store.FindDeadLetters(ctx, list)
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoStore keeps bookings and logs in 'techathon_db'.
+type mongoStore struct {
+	historyLimit int // BOOKING_HISTORY_LIMIT
+	writeRetries int // MONGO_WRITE_RETRIES
+}
+
+func (mongoStore) FindActiveBooking(ctx context.Context, vehicleID string) (DBBooking, error) {
+	var booking DBBooking
+	err := bookingCollection.FindOne(ctx, activeVehicleFilter(vehicleID)).Decode(&booking)
+	if err == mongo.ErrNoDocuments {
+		return booking, errBookingNotFound
+	}
+	return booking, err
+}
+
+func (mongoStore) FindBookingByCode(ctx context.Context, confirmationCode string) (DBBooking, error) {
+	var booking DBBooking
+	opts := options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	err := bookingCollection.FindOne(ctx, bson.M{"confirmationCode": confirmationCode}, opts).Decode(&booking)
+	if err == mongo.ErrNoDocuments {
+		return booking, errBookingNotFound
+	}
+	return booking, err
+}
+
+// bookingFilter turns a BookingQuery into a filter.
+func bookingFilter(q BookingQuery) bson.M {
+	filter := bson.M{}
+	if q.VehicleID != "" {
+		filter["vehicleId"] = q.VehicleID
+	}
+	if q.UserID != "" {
+		filter["userId"] = q.UserID
+	}
+	if q.Action != "" {
+		filter["action"] = q.Action
+	}
+	if q.Anonymous {
+		filter["userId"] = bson.M{"$in": bson.A{nil, "", AnonymousUserID}}
+	}
+	if q.Status != "" {
+		filter["status"] = q.Status
+	}
+	if q.CodePrefix != "" {
+		// Anchored and case-sensitive, so the confirmationCode index is used.
+		filter["confirmationCode"] = bson.M{"$regex": "^" + regexp.QuoteMeta(q.CodePrefix)}
+	}
+	if q.ActiveOnly && q.Status == "" {
+		filter["status"] = bson.M{"$nin": inactiveStatuses}
+	}
+	if q.CenterID != "" {
+		filter["scheduledService.serviceCenterId"] = q.CenterID
+	}
+	if q.Unassigned {
+		filter["scheduledService.serviceCenterId"] = bson.M{"$in": bson.A{nil, "", "null"}}
+	}
+	for key, value := range q.Metadata {
+		filter["metadata."+key] = value
+	}
+	if !q.CreatedBefore.IsZero() {
+		filter["createdAt"] = bson.M{"$lt": q.CreatedBefore}
+	}
+	// dateTime is stored as an RFC3339 UTC string, which compares in time order.
+	scheduled := bson.M{}
+	if !q.ScheduledFrom.IsZero() {
+		scheduled["$gte"] = formatTimestamp(q.ScheduledFrom)
+	}
+	if !q.ScheduledTo.IsZero() {
+		scheduled["$lt"] = formatTimestamp(q.ScheduledTo)
+	}
+	if len(scheduled) > 0 {
+		filter["scheduledService.dateTime"] = scheduled
+	}
+	if q.ReminderDue {
+		filter["reminderSentAt"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+func (s mongoStore) FindBookings(ctx context.Context, q BookingQuery) ([]DBBooking, int64, error) {
+	total, err := s.CountBookings(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := bookingCollection.Find(ctx, bookingFilter(q), q.List.apply(options.Find()))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	bookings := []DBBooking{}
+	if err := cursor.All(ctx, &bookings); err != nil {
+		return nil, 0, err
+	}
+	return bookings, total, nil
+}
+
+func (mongoStore) CountBookings(ctx context.Context, q BookingQuery) (int64, error) {
+	return bookingCollection.CountDocuments(ctx, bookingFilter(q))
+}
+
+func (mongoStore) EachBooking(ctx context.Context, q BookingQuery, fn func(DBBooking) error) error {
+	cursor, err := bookingCollection.Find(ctx, bookingFilter(q), q.List.apply(options.Find()))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var booking DBBooking
+		if err := cursor.Decode(&booking); err != nil {
+			return err
+		}
+		if err := fn(booking); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (s mongoStore) SaveBookingWithLog(ctx context.Context, booking DBBooking, isUpdate bool, changes []ChangeEvent, entry LogEntry) (bool, error) {
+	booking = withUserID(booking)
+	writeBooking := func(ctx context.Context) error {
+		if isUpdate {
+			// createdAt is left as it was.
+			update := bson.M{
+				"$set": bson.M{
+					"company":          booking.Company,
+					"confirmationCode": booking.ConfirmationCode,
+					"status":           booking.Status,
+					"scheduledService": booking.ScheduledService,
+					"priority":         booking.Priority,
+					"action":           booking.Action,
+					"metadata":         booking.Metadata,
+					"userId":           booking.UserID,
+					"updatedAt":        booking.UpdatedAt,
+				},
+			}
+			if len(changes) > 0 {
+				update["$push"] = s.pushHistory(changes...)
+			}
+			_, err := bookingCollection.UpdateOne(ctx, activeVehicleFilter(booking.VehicleID), update)
+			return err
+		}
+		booking.History = appendHistory(nil, changes, s.historyLimit)
+		_, err := bookingCollection.InsertOne(ctx, booking)
+		return err
+	}
+	writeLog := func(ctx context.Context) error {
+		_, err := logsCollection.InsertOne(ctx, entry)
+		return err
+	}
+	return saveBookingWithLog(ctx, s.writeRetries, writeBooking, writeLog)
+}
+
+func (mongoStore) AddBookingNote(ctx context.Context, confirmationCode string, note Note) (DBBooking, error) {
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetReturnDocument(options.After)
+	var booking DBBooking
+	err := bookingCollection.FindOneAndUpdate(ctx, bson.M{"confirmationCode": confirmationCode}, bson.M{"$push": bson.M{"notes": note}}, opts).Decode(&booking)
+	if err == mongo.ErrNoDocuments {
+		return booking, errBookingNotFound
+	}
+	return booking, err
+}
+
+// bookingIdentity matches exactly one booking.
+func bookingIdentity(booking DBBooking) bson.M {
+	return bson.M{"vehicleId": booking.VehicleID, "confirmationCode": booking.ConfirmationCode, "createdAt": booking.CreatedAt}
+}
+
+func (mongoStore) ClaimReminder(ctx context.Context, booking DBBooking, at time.Time) error {
+	filter := bookingIdentity(booking)
+	filter["reminderSentAt"] = bson.M{"$exists": false}
+	res, err := bookingCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"reminderSentAt": at}})
+	if err == nil && res.MatchedCount == 0 {
+		return errBookingNotFound
+	}
+	return err
+}
+
+func (mongoStore) ReleaseReminder(ctx context.Context, booking DBBooking) error {
+	_, err := bookingCollection.UpdateOne(ctx, bookingIdentity(booking), bson.M{"$unset": bson.M{"reminderSentAt": ""}})
+	return err
+}
+
+func (mongoStore) InsertLog(ctx context.Context, entry LogEntry) error {
+	_, err := logsCollection.InsertOne(ctx, entry)
+	return err
+}
+
+// logFilter turns a LogQuery into a filter. The prefix becomes a
+// case-sensitive regex anchored at the start, which MongoDB can answer from an
+// index on 'data.action'.
+func logFilter(q LogQuery) bson.M {
+	filter := bson.M{}
+	if q.Action != "" {
+		filter["data.action"] = q.Action
+	}
+	if q.ActionPrefix != "" {
+		filter["data.action"] = bson.M{"$regex": "^" + regexp.QuoteMeta(q.ActionPrefix)}
+	}
+	// Timestamps are stored as RFC3339 UTC strings, which compare in time order.
+	timestamp := bson.M{}
+	if !q.From.IsZero() {
+		timestamp["$gte"] = formatTimestamp(q.From)
+	}
+	if !q.To.IsZero() {
+		timestamp["$lt"] = formatTimestamp(q.To)
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+	return filter
+}
+
+func (mongoStore) CountLogs(ctx context.Context, q LogQuery) (int64, error) {
+	return logsCollection.CountDocuments(ctx, logFilter(q))
+}
+
+func (mongoStore) EachLog(ctx context.Context, q LogQuery, fn func(LogEntry) error) error {
+	cursor, err := logsCollection.Find(ctx, logFilter(q), q.List.apply(options.Find()))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry LogEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// pushHistory appends changes to 'history', keeping the newest
+// historyLimit events.
+func (s mongoStore) pushHistory(changes ...ChangeEvent) bson.M {
+	return bson.M{"history": bson.M{"$each": changes, "$slice": -s.historyLimit}}
+}
+
+func (s mongoStore) SetBookingStatus(ctx context.Context, booking DBBooking, status, actor string, entry LogEntry) error {
+	filter := bson.M{
+		"vehicleId":        booking.VehicleID,
+		"confirmationCode": booking.ConfirmationCode,
+		"status":           booking.Status,
+	}
+	update := bson.M{
+		"$set":  bson.M{"status": status, "updatedAt": time.Now().UTC()},
+		"$push": s.pushHistory(statusChange(booking, status, actor)),
+	}
+	_, err := saveBookingWithLog(ctx, s.writeRetries,
+		func(ctx context.Context) error {
+			res, err := bookingCollection.UpdateOne(ctx, filter, update)
+			if err == nil && res.MatchedCount == 0 {
+				return errBookingNotFound
+			}
+			return err
+		},
+		func(ctx context.Context) error {
+			_, err := logsCollection.InsertOne(ctx, entry)
+			return err
+		},
+	)
+	return err
+}
+
+func (s mongoStore) SaveDeadLetter(ctx context.Context, dl DeadLetter) error {
+	return withWriteRetry(ctx, s.writeRetries, "Dead letter write", func() error {
+		_, err := deadLettersCollection.ReplaceOne(ctx, bson.M{"deadLetterId": dl.ID}, dl, options.Replace().SetUpsert(true))
+		return err
+	})
+}
+
+func (mongoStore) FindDeadLetter(ctx context.Context, id string) (DeadLetter, error) {
+	var dl DeadLetter
+	err := deadLettersCollection.FindOne(ctx, bson.M{"deadLetterId": id}).Decode(&dl)
+	if err == mongo.ErrNoDocuments {
+		return dl, errDeadLetterNotFound
+	}
+	return dl, err
+}
+
+func (mongoStore) ClaimDeadLetterReplay(ctx context.Context, id string, at time.Time) (DeadLetter, error) {
+	// A claimed replay has no replayStatus until it finishes, so it matches
+	// neither branch; one that failed may be claimed again.
+	filter := bson.M{"deadLetterId": id, "$or": bson.A{
+		bson.M{"replayedAt": bson.M{"$exists": false}},
+		bson.M{"replayStatus": bson.M{"$gte": replayFailedStatus}},
+	}}
+	update := bson.M{"$set": bson.M{"replayedAt": at}, "$unset": bson.M{"replayStatus": ""}}
+	var dl DeadLetter
+	err := deadLettersCollection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&dl)
+	if err == mongo.ErrNoDocuments {
+		return dl, errDeadLetterNotFound
+	}
+	return dl, err
+}
+
+func (mongoStore) FindDeadLetters(ctx context.Context, list ListParams) ([]DeadLetter, int64, error) {
+	total, err := deadLettersCollection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+	cursor, err := deadLettersCollection.Find(ctx, bson.M{}, list.apply(options.Find()))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	deadLetters := []DeadLetter{}
+	if err := cursor.All(ctx, &deadLetters); err != nil {
+		return nil, 0, err
+	}
+	return deadLetters, total, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// handleManualSync re-pushes one booking to its center in 'auto_ai_db', for
+// when the background push after booking failed and the cause has been fixed.
+// A booking the center already lists is left alone so retries don't double up.
+func handleManualSync(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		booking, err := store.FindBookingByCode(ctx, c.Param("confirmationCode"))
+		if err == errBookingNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found", "code": "BOOKING_NOT_FOUND"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking"})
+			return
+		}
+
+		centerID := booking.ScheduledService.ServiceCenterID
+		if centerID == "" || centerID == "null" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Booking has no service center to sync to", "code": "NO_CENTER"})
+			return
+		}
+		for _, status := range inactiveStatuses {
+			if booking.Status == status {
+				c.JSON(http.StatusConflict, gin.H{"error": "Booking is " + booking.Status + " and is not synced", "code": "BOOKING_INACTIVE"})
+				return
+			}
+		}
+
+		// A booking that failed its required sync is confirmed once it's there.
+		syncPending := booking.Status == StatusSyncPending
+		synced := booking
+		if syncPending {
+			synced.Status = StatusConfirmed
+		}
+
+		fmt.Printf("🔄 Manual sync of %s -> Center: %s\n", booking.ConfirmationCode, centerID)
+		alreadySynced, err := pushBookingToCenter(ctx, synced)
+		if err != nil {
+			fmt.Printf("❌ Manual sync failed for %s: %v\n", booking.ConfirmationCode, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to update service center: " + err.Error(), "code": "SYNC_FAILED"})
+			return
+		}
+
+		logPersisted := true
+		entry := bookingLogEntry(cfg, synced, "MANUAL_SYNC")
+		if syncPending {
+			if err := store.SetBookingStatus(ctx, booking, StatusConfirmed, ActorOps, entry); err != nil {
+				fmt.Printf("⚠️ Synced %s but could not confirm it: %v\n", booking.ConfirmationCode, err)
+				logPersisted = false
+			}
+		} else if err := store.InsertLog(ctx, entry); err != nil {
+			fmt.Printf("⚠️ Could not log manual sync for %s: %v\n", booking.ConfirmationCode, err)
+			logPersisted = false
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"confirmationCode": booking.ConfirmationCode,
+			"serviceCenterId":  centerID,
+			"synced":           true,
+			"alreadySynced":    alreadySynced,
+			"bookingStatus":    synced.Status,
+			"logPersisted":     logPersisted,
+		})
+	}
+}
+
+// respondRequiredSyncFailed fails a booking for a REQUIRE_SYNC_COMPANIES
+// company whose push to the center failed. The booking stays saved as
+// SYNC_PENDING, so it is still the vehicle's active booking, until POST
+// /bookings/:code/sync gets it to the center and confirms it.
+//
+// Only client-chosen bookings take this path, and those never reserve
+// capacity in 'auto_ai_db': the push is what lists them in the center's
+// 'bookings' array. So a SYNC_PENDING booking holds no center capacity and
+// there is nothing to release. Auto-assigned bookings are the ones that keep
+// capacity, reserved before their write.
+func respondRequiredSyncFailed(ctx context.Context, cfg Config, c *gin.Context, req IncomingBookingRequest, opts BookingOptions, booking DBBooking, syncErr error) {
+	fmt.Printf("❌ Required sync failed for %s: %v\n", booking.VehicleID, syncErr)
+	// The booking deadline may be what failed the push; the bookkeeping still runs.
+	cleanupCtx, cleanupCancel := context.WithTimeout(context.WithoutCancel(ctx), deadlineWriteGrace)
+	defer cleanupCancel()
+
+	entry := bookingLogEntry(cfg, booking, "SYNC_FAILED")
+	entry.Data.Status = StatusSyncPending
+	if err := store.SetBookingStatus(cleanupCtx, booking, StatusSyncPending, ActorSystem, entry); err != nil {
+		fmt.Printf("⚠️ Could not mark %s as %s: %v\n", booking.VehicleID, StatusSyncPending, err)
+	}
+	booking.Status = StatusSyncPending
+	if !errors.Is(syncErr, errCenterSourceUnavailable) {
+		recordDeadLetter(cleanupCtx, cfg, DeadLetterSync, req, opts, &booking, syncErr)
+	}
+
+	c.JSON(http.StatusBadGateway, gin.H{
+		"error":            "Booking saved but could not be synced to the service center, so it is not confirmed: " + syncErr.Error(),
+		"code":             "SYNC_FAILED",
+		"bookingStatus":    StatusSyncPending,
+		"confirmationCode": booking.ConfirmationCode,
+		"assignedCenter":   booking.ScheduledService.ServiceCenterID,
+	})
+}
+
+// pushBookingToCenter adds the booking to its center's 'bookings' array
+// unless the center already lists it.
+func pushBookingToCenter(ctx context.Context, booking DBBooking) (alreadySynced bool, err error) {
+	if serviceCenterCollection == nil {
+		return false, errCenterSourceUnavailable
+	}
+	alreadySynced, err = centerHasBooking(ctx, booking)
+	if err != nil || alreadySynced {
+		return alreadySynced, err
+	}
+	return false, updateRemoteServiceCenter(ctx, booking)
+}
+
+// centerHasBooking reports whether the booking's center already lists it.
+func centerHasBooking(ctx context.Context, booking DBBooking) (bool, error) {
+	n, err := serviceCenterCollection.CountDocuments(ctx, bson.M{
+		"centerId": booking.ScheduledService.ServiceCenterID,
+		"bookings": bson.M{"$elemMatch": bson.M{
+			"vehicleId":        booking.VehicleID,
+			"confirmationCode": booking.ConfirmationCode,
+		}},
+	})
+	return n > 0, err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // center timezones must resolve in minimal containers too
+)
+
+// Layout for clients that send RFC3339 without a zone offset; treated as UTC.
+const rfc3339NoZone = "2006-01-02T15:04:05"
+
+// parseFlexibleTime accepts the timestamp formats clients actually send:
+// RFC3339, RFC3339 without a timezone (assumed UTC) and numeric Unix epoch
+// in seconds or milliseconds.
+func parseFlexibleTime(value string) (time.Time, error) {
+	return parseFlexibleTimeIn(value, time.UTC)
+}
+
+// parseFlexibleTimeIn is parseFlexibleTime with timestamps that carry no
+// timezone read as local time in loc.
+func parseFlexibleTimeIn(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("timestamp is empty")
+	}
+
+	if isDigits(value) {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("epoch %q is out of range", value)
+		}
+		// 10 digits of seconds covers dates up to the year 2286, so anything
+		// longer is an epoch in milliseconds.
+		if len(value) > 10 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(rfc3339NoZone, value, loc); err == nil {
+		return t.UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported timestamp %q: expected RFC3339 (e.g. 2024-05-01T10:00:00Z), RFC3339 without timezone, or Unix epoch seconds/milliseconds", value)
+}
+
+// centerLocation resolves a center's IANA timezone, e.g. "Asia/Kolkata".
+// Centers without one, or with one we don't know, keep the UTC behaviour.
+func centerLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		fmt.Printf("⚠️ Unknown service center timezone %q; using UTC\n", timezone)
+		return time.UTC
+	}
+	return loc
+}
+
+// localizeTo stores the requested time as UTC, reading a time without an
+// offset as local time at the center. The center's timezone is kept with the
+// booking so the time can be shown in local time again.
+func (s *ScheduledService) localizeTo(requested, timezone string) {
+	loc := centerLocation(timezone)
+	if loc != time.UTC {
+		s.Timezone = loc.String()
+	}
+	if requested == "" {
+		return
+	}
+	if t, err := parseFlexibleTimeIn(requested, loc); err == nil {
+		s.DateTime = formatTimestamp(t)
+	}
+}
+
+// LocalDateTime is the scheduled time at the center, for display.
+func (s ScheduledService) LocalDateTime() string {
+	t, err := time.Parse(time.RFC3339, s.DateTime)
+	if err != nil {
+		return s.DateTime
+	}
+	return t.In(centerLocation(s.Timezone)).Format(time.RFC3339)
+}
+
+// scheduledInPast reports whether a stored RFC3339 time lies more than grace
+// before now. Empty and unparseable values are not in the past.
+func scheduledInPast(dateTime string, grace time.Duration) bool {
+	t, err := time.Parse(time.RFC3339, dateTime)
+	return err == nil && t.Before(time.Now().Add(-grace))
+}
+
+// formatTimestamp is the single format we persist timestamps in.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// --- ATOMIC BOOKING + LOG WRITES ---
+
+// bookingWriteConcern is the write concern booking transactions commit with,
+// matching the one configured on the Bookings and Logs collections.
+var bookingWriteConcern *writeconcern.WriteConcern
+
+// transactionsUnsupported is set the first time the server rejects a
+// transaction, which happens on standalone (non replica set) deployments.
+var transactionsUnsupported atomic.Bool
+
+// isTransactionUnsupported matches "Transaction numbers are only allowed on a
+// replica set member or mongos" (IllegalOperation).
+func isTransactionUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == 20
+}
+
+// saveBookingWithLog commits the booking write and its audit log together.
+// On deployments without transaction support it falls back to two
+// independent (retried) writes; there a failed log insert does not fail the
+// booking, and logPersisted reports whether the audit entry made it.
+func saveBookingWithLog(ctx context.Context, retries int, writeBooking, writeLog func(context.Context) error) (logPersisted bool, err error) {
+	ctx, span := tracer.Start(ctx, "saveBookingWithLog")
+	defer func() { endSpanError(span, err); span.End() }()
+	writeBooking = tracedWrite("writeBooking", writeBooking)
+	writeLog = tracedWrite("writeLog", writeLog)
+
+	if !transactionsUnsupported.Load() {
+		err := runInTransaction(ctx, func(txCtx context.Context) error {
+			if err := writeBooking(txCtx); err != nil {
+				return err
+			}
+			return writeLog(txCtx)
+		})
+		if !isTransactionUnsupported(err) {
+			return err == nil, err
+		}
+		transactionsUnsupported.Store(true)
+		fmt.Println("⚠️ MongoDB deployment does not support transactions. Booking and log writes are no longer atomic.")
+	}
+
+	if err := withWriteRetry(ctx, retries, "Booking write", func() error { return writeBooking(ctx) }); err != nil {
+		return false, err
+	}
+	if err := withWriteRetry(ctx, retries, "Log insert", func() error { return writeLog(ctx) }); err != nil {
+		fmt.Println("Error saving log:", err)
+		return false, nil
+	}
+	return true, nil
+}
+
+func tracedWrite(name string, write func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error { return traced(ctx, name, write) }
+}
+
+func runInTransaction(ctx context.Context, fn func(context.Context) error) error {
+	sessionOpts := options.Session()
+	if bookingWriteConcern != nil {
+		sessionOpts.SetDefaultWriteConcern(bookingWriteConcern)
+	}
+	session, err := client.StartSession(sessionOpts)
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
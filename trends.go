@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// defaultTrendRange is the window GET /bookings/trends covers without ?from=.
+const defaultTrendRange = 30 * 24 * time.Hour
+
+// TrendBucket is the number of bookings created in one period. Counts splits
+// the total when ?by= is set.
+type TrendBucket struct {
+	Start  time.Time      `json:"start"`
+	Total  int            `json:"total"`
+	Counts map[string]int `json:"counts,omitempty"`
+}
+
+// handleBookingTrends counts bookings per day, week (starting Monday) or
+// month of 'createdAt', optionally split by status or company, for the
+// dashboard's charts. Periods without bookings are left out.
+func handleBookingTrends(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granularity := c.DefaultQuery("granularity", "day")
+		if granularity != "day" && granularity != "week" && granularity != "month" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be one of: day, week, month"})
+			return
+		}
+
+		var group interface{}
+		switch by := c.Query("by"); by {
+		case "":
+		case "status":
+			group = "$status"
+		case "company":
+			// Bookings from before 'company' was stored fall back to the vehicleId.
+			derived := bson.M{"$arrayElemAt": bson.A{bson.M{"$split": bson.A{"$vehicleId", companyDelimiter}}, 0}}
+			group = bson.M{"$ifNull": bson.A{"$company", derived}}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "by must be status or company"})
+			return
+		}
+
+		to := time.Now().UTC()
+		if value := c.Query("to"); value != "" {
+			var err error
+			if to, _, err = parseExportBound(value, true); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to: " + err.Error()})
+				return
+			}
+		}
+		from := to.Add(-defaultTrendRange)
+		if value := c.Query("from"); value != "" {
+			var err error
+			if from, _, err = parseExportBound(value, false); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from: " + err.Error()})
+				return
+			}
+		}
+		if !from.Before(to) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		// $dateTrunc needs MongoDB 5.0 or newer.
+		pipeline := []bson.M{
+			{"$match": bson.M{"createdAt": bson.M{"$gte": from, "$lt": to}}},
+			{"$group": bson.M{
+				"_id": bson.M{
+					"start": bson.M{"$dateTrunc": bson.M{"date": "$createdAt", "unit": granularity, "startOfWeek": "monday"}},
+					"group": group,
+				},
+				"count": bson.M{"$sum": 1},
+			}},
+			{"$sort": bson.M{"_id.start": 1}},
+		}
+		cursor, err := bookingCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate bookings"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var rows []struct {
+			ID struct {
+				Start time.Time `bson:"start"`
+				Group string    `bson:"group"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		}
+		if err = cursor.All(ctx, &rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding booking trends"})
+			return
+		}
+
+		buckets := []TrendBucket{}
+		for _, row := range rows {
+			if n := len(buckets); n == 0 || !buckets[n-1].Start.Equal(row.ID.Start) {
+				buckets = append(buckets, TrendBucket{Start: row.ID.Start.UTC()})
+			}
+			bucket := &buckets[len(buckets)-1]
+			bucket.Total += row.Count
+			if group != nil {
+				if bucket.Counts == nil {
+					bucket.Counts = map[string]int{}
+				}
+				bucket.Counts[row.ID.Group] += row.Count
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"granularity": granularity,
+			"from":        formatTimestamp(from),
+			"to":          formatTimestamp(to),
+			"buckets":     buckets,
+		})
+	}
+}
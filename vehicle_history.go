@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleVehicleBookings returns every booking a vehicle has had, cancelled and
+// completed ones included, newest first unless ?sort=/?order= say otherwise.
+// ?status= narrows it to one status.
+func handleVehicleBookings(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := parseListParams(c, "createdAt", "updatedAt")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if list.SortField == "" {
+			list.SortField = "createdAt"
+		}
+
+		q := BookingQuery{
+			VehicleID: c.Param("vehicleId"),
+			Status:    c.Query("status"),
+			List:      list,
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), cfg.RequestTimeout)
+		defer cancel()
+
+		bookings, total, err := store.FindBookings(ctx, q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+			return
+		}
+		setPaginationHeaders(c, total, list.Page)
+		c.JSON(http.StatusOK, bookings)
+	}
+}
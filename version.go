@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build metadata, injected at build time:
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildTime = "unknown"
+)
+
+func buildInfo() string {
+	return fmt.Sprintf("version %s, commit %s, built %s, %s", version, commit, buildTime, runtime.Version())
+}
+
+func handleVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"version":   version,
+		"commit":    commit,
+		"buildTime": buildTime,
+		"goVersion": runtime.Version(),
+	})
+}
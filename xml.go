@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/xml"
+	"maps"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// --- XML FOR LEGACY CONSUMERS ---
+
+// BookingMetadata is a booking's free-form metadata. encoding/xml can't
+// handle maps, so in XML it is written as <entry key="...">value</entry>.
+type BookingMetadata map[string]string
+
+type metadataEntryXML struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func (m BookingMetadata) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		entry := metadataEntryXML{Key: key, Value: m[key]}
+		if err := e.EncodeElement(entry, xml.StartElement{Name: xml.Name{Local: "entry"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (m *BookingMetadata) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var entries struct {
+		Entries []metadataEntryXML `xml:"entry"`
+	}
+	if err := d.DecodeElement(&entries, &start); err != nil {
+		return err
+	}
+	*m = BookingMetadata{}
+	for _, entry := range entries.Entries {
+		(*m)[entry.Key] = entry.Value
+	}
+	return nil
+}
+
+// bookingListXML wraps a booking list in a single <bookings> root element.
+type bookingListXML struct {
+	XMLName  xml.Name    `xml:"bookings"`
+	Bookings []DBBooking `xml:"booking"`
+}
+
+// wantsXML reports whether the Accept header prefers XML over JSON. JSON
+// stays the default, including for */* and a missing header.
+func wantsXML(c *gin.Context) bool {
+	switch c.NegotiateFormat(binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2) {
+	case binding.MIMEXML, binding.MIMEXML2:
+		return true
+	}
+	return false
+}